@@ -0,0 +1,28 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// checkExecutable reports whether files created in dir may be executed. On
+// Linux, access(2) with X_OK fails for files on noexec mounts.
+func checkExecutable(dir string) error {
+	probe := filepath.Join(dir, ".exec-probe")
+	if err := os.WriteFile(probe, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		return err
+	}
+	defer os.Remove(probe)
+
+	if err := syscall.Access(probe, 0x1); err != nil { // X_OK
+		if errors.Is(err, syscall.EACCES) {
+			return errors.New("filesystem is mounted noexec")
+		}
+		return err
+	}
+	return nil
+}
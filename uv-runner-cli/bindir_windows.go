@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+// checkExecutable always succeeds on Windows, which has no noexec mounts.
+func checkExecutable(dir string) error {
+	return nil
+}
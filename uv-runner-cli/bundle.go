@@ -0,0 +1,613 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// A bundle is a zip archive for running scripts on machines without network
+// access. Like a payload it holds a manifest.json, the scripts under scripts/
+// and the uv release archive, and in addition a uv.lock-style lock file next
+// to each script (<script>.lock), the uv cache populated with the locked
+// dependencies under cache/ and, optionally, a uv-managed Python under
+// python/. A bundle only works on the platform it was exported on.
+const (
+	bundleScriptsDir = "scripts"
+	bundleCacheDir   = "cache"
+	bundlePythonDir  = "python"
+	bundleBinDir     = "bin"
+)
+
+// runBundle implements `uv-runner-cli bundle`, which exports scripts with
+// everything they need into an archive and installs and runs such archives.
+func runBundle(cfg *Config, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "export":
+			return runBundleExport(cfg, args[1:])
+		case "import":
+			return runBundleImport(cfg, args[1:])
+		case "run":
+			return runBundleRun(cfg, args[1:])
+		}
+	}
+	name := filepath.Base(os.Args[0])
+	fmt.Printf("Usage: %s bundle export -o <bundle.zip> [flags] [script-or-url...]\n", name)
+	fmt.Printf("       %s bundle import [-dir <dir>] <bundle.zip>\n", name)
+	fmt.Printf("       %s bundle run <name-or-dir> [args...]\n", name)
+	if len(args) == 0 {
+		return fmt.Errorf("expected a bundle command")
+	}
+	return fmt.Errorf("unknown bundle command %q", args[0])
+}
+
+// runBundleExport locks and caches the dependencies of the scripts with the
+// uv release that goes into the bundle, then writes the bundle.
+func runBundleExport(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("bundle export", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s bundle export -o <bundle.zip> [flags] [script-or-url...]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	output := fs.String("o", "", "path of the bundle to write (required)")
+	name := fs.String("name", "", "name the bundle is installed under (default: derived from -o)")
+	withPython := fs.Bool("with-python", true, "include a uv-managed Python, for machines without a suitable one")
+	approveScripts := fs.Bool("approve-scripts", false, "export remote scripts that are new or changed since they were last approved")
+	fs.Parse(args)
+
+	if *output == "" {
+		fs.Usage()
+		return fmt.Errorf("-o is required")
+	}
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(*output), filepath.Ext(*output))
+	}
+	*name = strings.Trim(unsafeFileChars.ReplaceAllString(*name, "-"), "-")
+	if *name == "" {
+		return fmt.Errorf("invalid bundle name")
+	}
+
+	target, err := detectTarget()
+	if err != nil {
+		return err
+	}
+	tempDir, err := makeRuntimeDir(cfg, logStdout)
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// The bundle's own uv does the locking, so the lock files suit it
+	uv, archive, err := fetchUVForPayload(cfg, target)
+	if err != nil {
+		return err
+	}
+	uvPath, err := extractUVData(archive, tempDir, target)
+	if err != nil {
+		return err
+	}
+
+	staging := filepath.Join(tempDir, "bundle")
+	scripts, err := stageBundleScripts(cfg, fs.Args(), tempDir, staging, approveFlag(*approveScripts))
+	if err != nil {
+		return err
+	}
+
+	env, err := cfg.uvEnv(tempDir)
+	if err != nil {
+		return err
+	}
+	env = append(os.Environ(), env...)
+	env = append(env, bundleEnv(staging, *withPython)...)
+	if *withPython {
+		fmt.Println("Installing Python...")
+		install := []string{"python", "install"}
+		if cfg.Python != "" {
+			install = append(install, cfg.Python)
+		}
+		if err := runUVStep(uvPath, env, install...); err != nil {
+			return fmt.Errorf("failed to install Python: %w", err)
+		}
+	}
+	for _, script := range scripts {
+		file := filepath.Join(staging, filepath.FromSlash(script))
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		metadata, err := parseScriptMetadata(source)
+		if err != nil {
+			return fmt.Errorf("failed to read metadata of %s: %w", path.Base(script), err)
+		}
+		if metadata == nil {
+			continue
+		}
+		fmt.Printf("Locking and caching dependencies of %s...\n", path.Base(script))
+		if err := runUVStep(uvPath, env, "lock", "--script", file); err != nil {
+			return fmt.Errorf("failed to lock %s: %w", path.Base(script), err)
+		}
+		if err := runUVStep(uvPath, env, "sync", "--script", file); err != nil {
+			return fmt.Errorf("failed to cache dependencies of %s: %w", path.Base(script), err)
+		}
+	}
+
+	manifest := Manifest{Name: *name, Scripts: scripts, UV: uv}
+	if err := writeBundle(*output, manifest, archive, staging); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", *output)
+	return nil
+}
+
+// stageBundleScripts copies the scripts (the defaults when none are given)
+// into staging, fetching and checking remote ones, and returns their bundle
+// names.
+func stageBundleScripts(cfg *Config, refs []string, tempDir, staging string, approve approveFunc) ([]string, error) {
+	refs, _, err := chooseScripts(nil, refs, tempDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		if isToolEntry(ref) {
+			return nil, fmt.Errorf("cannot bundle %s: only scripts can be bundled", ref)
+		}
+	}
+	client, err := cfg.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	local, err := localScripts(context.Background(), newDownloader(client, logStdout), cfg, refs, filepath.Join(tempDir, "fetched"), approve)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(staging, bundleScriptsDir), 0755); err != nil {
+		return nil, err
+	}
+	var scripts []string
+	seen := map[string]bool{}
+	for _, file := range local {
+		name := path.Join(bundleScriptsDir, filepath.Base(file))
+		if seen[name] {
+			return nil, fmt.Errorf("two scripts are named %s", filepath.Base(file))
+		}
+		seen[name] = true
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(staging, filepath.FromSlash(name)), data, 0644); err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, name)
+	}
+	return scripts, nil
+}
+
+// bundleEnv points uv at the cache and Python installations of a bundle
+// rooted at dir.
+func bundleEnv(dir string, managedPython bool) []string {
+	env := []string{
+		"UV_CACHE_DIR=" + filepath.Join(dir, bundleCacheDir),
+		"UV_PYTHON_INSTALL_DIR=" + filepath.Join(dir, bundlePythonDir),
+	}
+	if managedPython {
+		env = append(env, "UV_PYTHON_PREFERENCE=only-managed")
+	}
+	return env
+}
+
+// runUVStep runs uv to completion with its output going to ours.
+func runUVStep(uvPath string, env []string, args ...string) error {
+	cmd := exec.Command(uvPath, args...)
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// extractUVData extracts the uv binary from an in-memory release archive.
+func extractUVData(archive []byte, dir, target string) (string, error) {
+	tmpFile, err := os.CreateTemp(dir, "uv-*-"+uvArchiveName(target))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+	if _, err := tmpFile.Write(archive); err != nil {
+		return "", err
+	}
+	return extractUVArchive(tmpFile, dir, target)
+}
+
+// writeBundle writes the manifest, the uv archive and the staged files to a
+// zip archive at output.
+func writeBundle(output string, manifest Manifest, archive []byte, staging string) error {
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(out)
+
+	err = func() error {
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		for name, content := range map[string][]byte{payloadManifest: data, manifest.UV.File: archive} {
+			w, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(content); err != nil {
+				return err
+			}
+		}
+		return filepath.WalkDir(staging, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || file == staging {
+				return err
+			}
+			rel, err := filepath.Rel(staging, file)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(rel)
+			// Script environments refer to absolute paths; uv recreates
+			// them from the cached packages
+			if d.IsDir() && strings.HasPrefix(name, bundleCacheDir+"/environments") {
+				return filepath.SkipDir
+			}
+			return addBundleFile(zw, file, name, d)
+		})
+	}()
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// addBundleFile adds a file, directory or relative symlink to the bundle,
+// keeping its permissions. Symlinks to absolute paths would not survive the
+// move to another machine and are left out.
+func addBundleFile(zw *zip.Writer, file, name string, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	var link string
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(file); err != nil {
+			return err
+		}
+		if filepath.IsAbs(link) {
+			return nil
+		}
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if d.IsDir() {
+		header.Name += "/"
+	} else {
+		header.Method = zip.Deflate
+	}
+	w, err := zw.CreateHeader(header)
+	if err != nil || d.IsDir() {
+		return err
+	}
+	if link != "" {
+		_, err = io.WriteString(w, filepath.ToSlash(link))
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// bundlesDir is where bundles are installed by default.
+func bundlesDir() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bundles"), nil
+}
+
+// runBundleImport installs a bundle.
+func runBundleImport(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("bundle import", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s bundle import [flags] <bundle.zip>\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", "", "directory to install the bundle into (default: bundles/<name> in the uv-runner data directory)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one bundle")
+	}
+	client, err := cfg.httpClient()
+	if err != nil {
+		return err
+	}
+	manifest, installed, err := importBundle(context.Background(), newDownloader(client, logStdout), cfg, fs.Arg(0), *dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Installed %s into %s\n", manifest.Name, installed)
+	fmt.Printf("Run it with: %s bundle run %s\n", filepath.Base(os.Args[0]), manifest.Name)
+	return nil
+}
+
+// importBundle installs the bundle in file into dir, or into bundles/<name>
+// when dir is empty, and returns its manifest and the directory. The uv
+// archive goes through the same verification as a download. Everything is
+// extracted into a staging directory next to dir first, so a failed import
+// leaves a previous installation untouched; an existing dir is only replaced
+// if it is empty or holds an installed bundle.
+func importBundle(ctx context.Context, dl *downloader, cfg *Config, file, dir string) (*Manifest, string, error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, "", err
+	}
+	defer zr.Close()
+	bundle := &payload{zip: &zr.Reader}
+	rc, err := zr.Open(payloadManifest)
+	if err != nil {
+		return nil, "", fmt.Errorf("bundle has no %s: %w", payloadManifest, err)
+	}
+	err = json.NewDecoder(rc).Decode(&bundle.manifest)
+	rc.Close()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	manifest := &bundle.manifest
+	if manifest.Name == "" || !filepath.IsLocal(manifest.Name) || manifest.UV == nil {
+		return nil, "", fmt.Errorf("%s is not a uv-runner bundle", file)
+	}
+
+	target, err := detectTarget()
+	if err != nil {
+		return nil, "", err
+	}
+	if manifest.UV.Target != target {
+		return nil, "", fmt.Errorf("bundle is for %s, but this machine is %s", manifest.UV.Target, target)
+	}
+	uv := bundle.embeddedUV(manifest.UV.Version, target)
+	if uv == nil {
+		return nil, "", fmt.Errorf("bundle does not contain %s", manifest.UV.File)
+	}
+
+	if dir == "" {
+		parent, err := bundlesDir()
+		if err != nil {
+			return nil, "", err
+		}
+		dir = filepath.Join(parent, manifest.Name)
+	}
+	replace, err := replaceableBundleDir(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, "", err
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+"-import-*")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(staging)
+
+	// Install uv from the bundle after the usual verification
+	binDir := filepath.Join(staging, bundleBinDir)
+	if err := os.Mkdir(binDir, 0755); err != nil {
+		return nil, "", err
+	}
+	tmpFile, err := os.CreateTemp(staging, "uv-*-"+uvArchiveName(target))
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+	if err := bundle.copyEmbeddedUV(ctx, dl, cfg, uv, tmpFile); err != nil {
+		return nil, "", err
+	}
+	if _, err := extractUVArchive(tmpFile, binDir, target); err != nil {
+		return nil, "", err
+	}
+	tmpFile.Close()
+	if err := os.Remove(tmpFile.Name()); err != nil {
+		return nil, "", err
+	}
+
+	if err := extractBundleFiles(zr.File, staging, uv.File); err != nil {
+		return nil, "", err
+	}
+
+	if replace {
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, "", fmt.Errorf("failed to remove previous installation: %w", err)
+		}
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		return nil, "", fmt.Errorf("failed to install bundle: %w", err)
+	}
+	return manifest, dir, nil
+}
+
+// replaceableBundleDir reports whether dir exists and may be replaced by an
+// import, and fails if it exists but is neither empty nor an installed bundle.
+func replaceableBundleDir(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(entries) == 0 {
+		return true, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, payloadManifest))
+	if err == nil {
+		var manifest Manifest
+		if json.Unmarshal(data, &manifest) == nil && manifest.Name != "" && manifest.UV != nil {
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("%s already exists and does not hold a bundle; choose another directory with -dir", dir)
+}
+
+// extractBundleFiles writes the manifest, scripts, cache and Python of a
+// bundle below dir, skipping the uv archive skip. All writes go through an
+// os.Root, so a symlink extracted earlier cannot redirect later entries
+// outside dir.
+func extractBundleFiles(files []*zip.File, dir, skip string) error {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	for _, f := range files {
+		if f.Name == skip {
+			continue
+		}
+		if err := extractBundleFile(f, root); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// extractBundleFile writes a file, directory or symlink from a bundle below
+// root, refusing anything that would end up outside it or outside the parts
+// of a bundle.
+func extractBundleFile(f *zip.File, root *os.Root) error {
+	name := strings.TrimSuffix(f.Name, "/")
+	if !filepath.IsLocal(name) || strings.Contains(name, "\\") {
+		return fmt.Errorf("path escapes the bundle directory")
+	}
+	switch top, _, _ := strings.Cut(name, "/"); top {
+	case payloadManifest, bundleScriptsDir, bundleCacheDir, bundlePythonDir:
+	default:
+		return fmt.Errorf("unexpected entry in bundle")
+	}
+	dest := filepath.FromSlash(name)
+	mode := f.Mode()
+	if mode.IsDir() {
+		return root.MkdirAll(dest, 0755)
+	}
+	if err := root.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if mode&fs.ModeSymlink != 0 {
+		link, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		target := filepath.FromSlash(string(link))
+		if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(filepath.Dir(dest), target)) {
+			return fmt.Errorf("symlink escapes the bundle directory")
+		}
+		return root.Symlink(target, dest)
+	}
+
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+	out, err := root.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm|0200)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// runBundleRun runs the scripts of an installed bundle offline, with any
+// further arguments passed to them.
+func runBundleRun(cfg *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s bundle run <name-or-dir> [args...]", filepath.Base(os.Args[0]))
+	}
+	dir := args[0]
+	if !strings.ContainsRune(dir, filepath.Separator) && !strings.Contains(dir, "/") {
+		parent, err := bundlesDir()
+		if err != nil {
+			return err
+		}
+		dir = filepath.Join(parent, dir)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, payloadManifest))
+	if err != nil {
+		return fmt.Errorf("bundle is not installed: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	if len(manifest.Scripts) == 0 {
+		return fmt.Errorf("bundle has no scripts")
+	}
+
+	uvName := "uv"
+	if runtime.GOOS == "windows" {
+		uvName = "uv.exe"
+	}
+	uvPath := filepath.Join(dir, bundleBinDir, uvName)
+
+	runArgs := []string{"run"}
+	for _, script := range manifest.Scripts {
+		if !filepath.IsLocal(filepath.FromSlash(script)) {
+			return fmt.Errorf("bundle script %q escapes the bundle directory", script)
+		}
+		runArgs = append(runArgs, filepath.Join(dir, filepath.FromSlash(script)))
+	}
+	runArgs = append(runArgs, manifest.Args...)
+	runArgs = append(runArgs, args[1:]...)
+
+	_, err = os.Stat(filepath.Join(dir, bundlePythonDir))
+	env := append(os.Environ(), bundleEnv(dir, err == nil)...)
+	env = append(env, "UV_OFFLINE=1")
+	env = append(env, manifest.environ()...)
+
+	fmt.Printf("Running %s offline...\n", manifest.Name)
+	return runUV(uvPath, runArgs, env, cfg.script(manifest.Scripts[0]).Detach)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// zipEntry is a member of a crafted archive; a mode with fs.ModeSymlink makes
+// body the link target.
+type zipEntry struct {
+	name string
+	mode fs.FileMode
+	body string
+}
+
+func buildZip(t *testing.T, entries []zipEntry) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Store}
+		mode := e.mode
+		if mode == 0 {
+			mode = 0644
+		}
+		hdr.SetMode(mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func TestExtractBundleFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []zipEntry
+		wantErr string
+	}{
+		{
+			name: "valid bundle",
+			entries: []zipEntry{
+				{name: "manifest.json", body: "{}"},
+				{name: "scripts/", mode: fs.ModeDir | 0755},
+				{name: "scripts/main.py", body: "print(1)"},
+				{name: "python/bin/python3.12", mode: 0755, body: "#!"},
+				{name: "python/bin/python3", mode: fs.ModeSymlink | 0777, body: "python3.12"},
+			},
+		},
+		{
+			name:    "parent traversal",
+			entries: []zipEntry{{name: "scripts/../../evil", body: "x"}},
+			wantErr: "escapes",
+		},
+		{
+			name:    "absolute path",
+			entries: []zipEntry{{name: "/tmp/evil", body: "x"}},
+			wantErr: "escapes",
+		},
+		{
+			name:    "backslash path",
+			entries: []zipEntry{{name: "scripts\\..\\..\\evil", body: "x"}},
+			wantErr: "escapes",
+		},
+		{
+			name:    "entry outside the bundle parts",
+			entries: []zipEntry{{name: "bin/uv", mode: 0755, body: "evil"}},
+			wantErr: "unexpected entry",
+		},
+		{
+			name:    "absolute symlink",
+			entries: []zipEntry{{name: "cache/link", mode: fs.ModeSymlink | 0777, body: "/etc"}},
+			wantErr: "symlink escapes",
+		},
+		{
+			name:    "relative symlink leaving the bundle",
+			entries: []zipEntry{{name: "cache/link", mode: fs.ModeSymlink | 0777, body: "../../outside"}},
+			wantErr: "symlink escapes",
+		},
+		{
+			// Each link stays inside on its own, but together they lead
+			// out of the bundle
+			name: "symlink chain",
+			entries: []zipEntry{
+				{name: "cache/a/b", mode: fs.ModeSymlink | 0777, body: ".."},
+				{name: "cache/a/b/c", mode: fs.ModeSymlink | 0777, body: ".."},
+				{name: "cache/a/b/c/d", mode: fs.ModeSymlink | 0777, body: ".."},
+				{name: "cache/a/b/c/d/evil", body: "x"},
+			},
+			wantErr: "extract",
+		},
+		{
+			name: "file written through a symlink",
+			entries: []zipEntry{
+				{name: "scripts/main.py", mode: fs.ModeSymlink | 0777, body: "../manifest.json"},
+				{name: "scripts/main.py", body: "overwrite"},
+			},
+			wantErr: "extract",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			dir := filepath.Join(base, "bundle")
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			zr := buildZip(t, tt.entries)
+			err := extractBundleFiles(zr.File, dir, "uv.tar.gz")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("extractBundleFiles() = %v", err)
+				}
+				if data, err := os.ReadFile(filepath.Join(dir, "python", "bin", "python3")); err != nil || string(data) != "#!" {
+					t.Errorf("python3 symlink: %q, %v", data, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("extractBundleFiles() = %v, want error containing %q", err, tt.wantErr)
+			}
+			// Nothing may appear next to the bundle directory
+			entries, err := os.ReadDir(base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("files written outside the bundle: %v", entries)
+			}
+		})
+	}
+}
+
+func TestReplaceableBundleDir(t *testing.T) {
+	base := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		file := filepath.Join(base, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("bundle/manifest.json", `{"name": "tools", "uv": {"version": "0.9.9", "target": "x", "file": "uv.tar.gz"}}`)
+	write("documents/report.txt", "keep me")
+	write("other/manifest.json", `{"name": "tools"}`)
+	if err := os.Mkdir(filepath.Join(base, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir     string
+		replace bool
+		wantErr bool
+	}{
+		{dir: "missing"},
+		{dir: "empty", replace: true},
+		{dir: "bundle", replace: true},
+		{dir: "documents", wantErr: true},
+		{dir: "other", wantErr: true},
+	}
+	for _, tt := range tests {
+		replace, err := replaceableBundleDir(filepath.Join(base, tt.dir))
+		if replace != tt.replace || (err != nil) != tt.wantErr {
+			t.Errorf("replaceableBundleDir(%s) = %v, %v; want %v, error %v", tt.dir, replace, err, tt.replace, tt.wantErr)
+		}
+	}
+}
+
+func TestImportBundleKeepsPreviousInstallation(t *testing.T) {
+	target, err := detectTarget()
+	if err != nil {
+		t.Skip(err)
+	}
+	base := t.TempDir()
+	bundleFile := filepath.Join(base, "tools.zip")
+	manifest := Manifest{
+		Name: "tools",
+		UV:   &EmbeddedUV{Version: "0.9.9", Target: target, File: "uv.tar.gz", SHA256: strings.Repeat("0", 64)},
+	}
+	if err := writeBundle(bundleFile, manifest, []byte("not the recorded archive"), t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(base, "installed")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := `{"name": "tools", "uv": {"version": "0.9.8", "target": "x", "file": "uv.tar.gz"}}`
+	if err := os.WriteFile(filepath.Join(dir, payloadManifest), []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = importBundle(t.Context(), newDownloader(newHTTPClient(), t.Logf), &Config{}, bundleFile, dir)
+	if err == nil || !strings.Contains(err.Error(), "damaged") {
+		t.Fatalf("importBundle() = %v, want a damaged archive error", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, payloadManifest)); err != nil || string(data) != old {
+		t.Errorf("previous installation changed: %q, %v", data, err)
+	}
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("staging directory left behind: %v", entries)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runCache implements `uv-runner-cli cache`, which manages files uv-runner
+// and the uv it runs leave on disk.
+func runCache(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("cache", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s cache clean|size|prune|wipe\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(fs.Output(), "  clean  remove session directories left behind by crashed uv-runner processes")
+		fmt.Fprintln(fs.Output(), "  size   show where uv's cache is and how large it is")
+		fmt.Fprintln(fs.Output(), "  prune  remove unused entries from uv's cache")
+		fmt.Fprintln(fs.Output(), "  wipe   remove uv's cache, and the Python installations of the profile")
+	}
+	fs.Parse(args)
+
+	switch fs.Arg(0) {
+	case "clean":
+		removed := sweepStaleDirs(cfg, "", logStdout)
+		fmt.Printf("Removed stale session directories: %d\n", removed)
+		return nil
+	case "size", "prune", "wipe":
+		return manageUVCache(cfg, fs.Arg(0))
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown cache command %q", fs.Arg(0))
+	}
+}
+
+// manageUVCache runs a cache command with the uv and environment scripts
+// run with.
+func manageUVCache(cfg *Config, command string) error {
+	embedded, err := openPayload()
+	if err != nil {
+		return fmt.Errorf("failed to read embedded payload: %w", err)
+	}
+	tempDir, err := makeRuntimeDir(cfg, logStdout)
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	uvPath, err := setUpUV(cfg, embedded, tempDir)
+	if err != nil {
+		return err
+	}
+	env, err := cfg.uvEnv(tempDir)
+	if err != nil {
+		return err
+	}
+	cache := &uvCache{cfg: cfg, uvPath: uvPath, env: append(os.Environ(), env...), logf: logStdout}
+
+	ctx := context.Background()
+	switch command {
+	case "prune":
+		err = cache.prune(ctx)
+	case "wipe":
+		err = cache.wipe(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	report, err := cache.report(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+	return nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Network defaults for the download client. There is deliberately no overall
+// request timeout: the uv archive is tens of megabytes and a slow but steady
+// connection should be allowed to finish. Instead each read must make progress
+// within readTimeout.
+const (
+	connectTimeout   = 15 * time.Second
+	readTimeout      = 30 * time.Second
+	downloadAttempts = 5
+	initialBackoff   = 1 * time.Second
+	maxBackoff       = 30 * time.Second
+)
+
+// logFunc receives progress messages; the CLI prints them and the GUI appends
+// them to its output pane.
+type logFunc func(format string, args ...any)
+
+// downloader fetches files over HTTP with timeouts, retries with exponential
+// backoff and Range-based resume of partial downloads.
+type downloader struct {
+	client      *http.Client
+	attempts    int
+	backoff     time.Duration
+	readTimeout time.Duration
+	logf        logFunc
+}
+
+func newDownloader(client *http.Client, logf logFunc) *downloader {
+	return &downloader{
+		client:      client,
+		attempts:    downloadAttempts,
+		backoff:     initialBackoff,
+		readTimeout: readTimeout,
+		logf:        logf,
+	}
+}
+
+// newHTTPClient returns a client with connect and response timeouts; see
+// Config.httpClient for the proxy and CA settings layered on top.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+	transport.ResponseHeaderTimeout = readTimeout
+	return &http.Client{Transport: transport}
+}
+
+// uvArchiveURL returns the GitHub release URL of the uv archive for target.
+func uvArchiveURL(version, target string) string {
+	return fmt.Sprintf("https://github.com/astral-sh/uv/releases/download/%s/%s", version, uvArchiveName(target))
+}
+
+// fetchUVArchive downloads the uv archive for target into f and verifies it.
+func fetchUVArchive(ctx context.Context, dl *downloader, cfg *Config, f *os.File, target string) error {
+	url := uvArchiveURL(cfg.uvVersion(), target)
+	dl.logf("Downloading uv from: %s\n", url)
+	if err := dl.fetchToFile(ctx, url, f); err != nil {
+		return fmt.Errorf("failed to download uv: %w", err)
+	}
+
+	dl.logf("Verifying checksum from: %s.sha256\n", url)
+	return verifyArchive(ctx, dl, cfg, f, cfg.uvVersion(), target)
+}
+
+// statusError is returned for unexpected HTTP status codes.
+type statusError struct {
+	url  string
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("GET %s: status %d", e.url, e.code)
+}
+
+// retryable reports whether a failed attempt is worth repeating: network
+// errors, truncated bodies, server errors and rate limiting are; client errors
+// such as 404 are not.
+func retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests || se.code == http.StatusRequestTimeout
+	}
+	return true
+}
+
+// retry runs fn until it succeeds, returns a permanent error, the attempts
+// are exhausted or ctx is cancelled, sleeping with exponential backoff in
+// between.
+func (d *downloader) retry(ctx context.Context, url string, fn func() error) error {
+	delay := d.backoff
+	var err error
+	for attempt := 1; attempt <= d.attempts; attempt++ {
+		if err = fn(); err == nil || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+		if attempt == d.attempts {
+			break
+		}
+		if d.logf != nil {
+			d.logf("Download of %s failed (%v), retrying in %s (attempt %d/%d)\n", url, err, delay, attempt+1, d.attempts)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// fetchToFile downloads url into f. If an attempt is interrupted, the next one
+// asks the server for the remaining bytes only and appends them to what is
+// already in f; servers that ignore the Range header cause a restart from
+// scratch.
+func (d *downloader) fetchToFile(ctx context.Context, url string, f *os.File) error {
+	return d.retry(ctx, url, func() error {
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		offset := info.Size()
+
+		body, resp, cancel, err := d.get(ctx, url, offset)
+		if err != nil {
+			var se *statusError
+			if errors.As(err, &se) && se.code == http.StatusRequestedRangeNotSatisfiable && offset > 0 {
+				// The partial file is already complete, or longer than the
+				// resource; start over and let the checksum decide.
+				if err := f.Truncate(0); err != nil {
+					return err
+				}
+				return fmt.Errorf("GET %s: partial download rejected, restarting", url)
+			}
+			return err
+		}
+		defer cancel()
+		defer body.Close()
+
+		if resp.StatusCode == http.StatusPartialContent {
+			if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+				if err := f.Truncate(0); err != nil {
+					return err
+				}
+				return fmt.Errorf("GET %s: unexpected Content-Range %q", url, resp.Header.Get("Content-Range"))
+			}
+			if d.logf != nil {
+				d.logf("Resuming download at byte %d\n", offset)
+			}
+		} else if offset > 0 {
+			if err := f.Truncate(0); err != nil {
+				return err
+			}
+			offset = 0
+		}
+
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		n, err := io.Copy(f, body)
+		if err != nil {
+			return fmt.Errorf("GET %s: %w", url, err)
+		}
+		if resp.ContentLength >= 0 && n < resp.ContentLength {
+			return fmt.Errorf("GET %s: %w", url, io.ErrUnexpectedEOF)
+		}
+		return nil
+	})
+}
+
+// fetchBytes downloads a small resource such as a checksum file into memory.
+func (d *downloader) fetchBytes(ctx context.Context, url string) ([]byte, error) {
+	var data []byte
+	err := d.retry(ctx, url, func() error {
+		body, _, cancel, err := d.get(ctx, url, 0)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		defer body.Close()
+		data, err = io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("GET %s: %w", url, err)
+		}
+		return nil
+	})
+	return data, err
+}
+
+// get issues a single GET request, starting at offset when it is non-zero.
+// The returned body fails if no data arrives within the read timeout; cancel
+// must be called once the body is no longer needed.
+func (d *downloader) get(ctx context.Context, url string, offset int64) (io.ReadCloser, *http.Response, context.CancelFunc, error) {
+	reqCtx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		cancel()
+		return nil, nil, nil, &statusError{url: url, code: resp.StatusCode}
+	}
+
+	body := &idleTimeoutReader{ReadCloser: resp.Body, timeout: d.readTimeout}
+	body.timer = time.AfterFunc(d.readTimeout, func() {
+		body.expired.Store(true)
+		cancel()
+	})
+	return body, resp, func() {
+		body.timer.Stop()
+		cancel()
+	}, nil
+}
+
+var errReadTimeout = errors.New("no data received within read timeout")
+
+// idleTimeoutReader cancels the underlying request when a read has not
+// returned any data within timeout.
+type idleTimeoutReader struct {
+	io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	expired atomic.Bool
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil && r.expired.Load() {
+		return n, errReadTimeout
+	}
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testBody is large enough that a dropped connection leaves a partial file.
+var testBody = bytes.Repeat([]byte("0123456789abcdef"), 4096)
+
+func testDownloader(t *testing.T, attempts int) *downloader {
+	return &downloader{
+		client:      newHTTPClient(),
+		attempts:    attempts,
+		backoff:     time.Millisecond,
+		readTimeout: time.Second,
+		logf:        t.Logf,
+	}
+}
+
+func tempFile(t *testing.T) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "download"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func fileContent(t *testing.T, f *os.File) []byte {
+	t.Helper()
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// dropHalf sends the headers for the whole body but only half of it, then
+// closes the connection.
+func dropHalf(w http.ResponseWriter) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(testBody)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(testBody[:len(testBody)/2])
+	w.(http.Flusher).Flush()
+	panic(http.ErrAbortHandler)
+}
+
+func TestFetchToFileResumes(t *testing.T) {
+	var requests atomic.Int32
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			dropHalf(w)
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		var start int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); err != nil {
+			t.Errorf("second request without Range header: %v", err)
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(testBody)-1, len(testBody)))
+		w.Header().Set("Content-Length", strconv.Itoa(len(testBody)-start))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(testBody[start:])
+	}))
+	defer srv.Close()
+
+	f := tempFile(t)
+	if err := testDownloader(t, 3).fetchToFile(t.Context(), srv.URL, f); err != nil {
+		t.Fatalf("fetchToFile() = %v", err)
+	}
+	if !bytes.Equal(fileContent(t, f), testBody) {
+		t.Error("resumed download differs from the original")
+	}
+	if want := fmt.Sprintf("bytes=%d-", len(testBody)/2); len(ranges) != 1 || ranges[0] != want {
+		t.Errorf("Range headers = %q, want [%q]", ranges, want)
+	}
+}
+
+func TestFetchToFileRestartsWhenRangeIsIgnored(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			dropHalf(w)
+		}
+		w.Write(testBody)
+	}))
+	defer srv.Close()
+
+	f := tempFile(t)
+	if err := testDownloader(t, 3).fetchToFile(t.Context(), srv.URL, f); err != nil {
+		t.Fatalf("fetchToFile() = %v", err)
+	}
+	if got := fileContent(t, f); !bytes.Equal(got, testBody) {
+		t.Errorf("got %d bytes, want the %d of a fresh download", len(got), len(testBody))
+	}
+}
+
+func TestFetchToFileRestartsOnWrongContentRange(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			dropHalf(w)
+		case 2:
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(testBody)-1, len(testBody)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(testBody)
+		default:
+			w.Write(testBody)
+		}
+	}))
+	defer srv.Close()
+
+	f := tempFile(t)
+	if err := testDownloader(t, 3).fetchToFile(t.Context(), srv.URL, f); err != nil {
+		t.Fatalf("fetchToFile() = %v", err)
+	}
+	if !bytes.Equal(fileContent(t, f), testBody) {
+		t.Error("download differs from the original")
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+}
+
+func TestFetchToFileIdleTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(testBody)))
+		w.Write(testBody[:100])
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	dl := testDownloader(t, 1)
+	dl.readTimeout = 100 * time.Millisecond
+	start := time.Now()
+	err := dl.fetchToFile(t.Context(), srv.URL, tempFile(t))
+	if !errors.Is(err, errReadTimeout) {
+		t.Fatalf("fetchToFile() = %v, want %v", err, errReadTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stalled download took %s to fail", elapsed)
+	}
+}
+
+func TestRetryExhaustion(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		want     int32
+	}{
+		{name: "server error is retried", status: http.StatusServiceUnavailable, attempts: 3, want: 3},
+		{name: "rate limiting is retried", status: http.StatusTooManyRequests, attempts: 2, want: 2},
+		{name: "not found is permanent", status: http.StatusNotFound, attempts: 3, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			_, err := testDownloader(t, tt.attempts).fetchBytes(t.Context(), srv.URL)
+			var se *statusError
+			if !errors.As(err, &se) || se.code != tt.status {
+				t.Fatalf("fetchBytes() = %v, want status %d", err, tt.status)
+			}
+			if n := requests.Load(); n != tt.want {
+				t.Errorf("%d requests, want %d", n, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// runEmbed implements `uv-runner-cli embed`, which writes a copy of a
+// uv-runner executable with scripts and the uv archive appended as a payload
+// so the copy runs offline.
+func runEmbed(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("embed", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s embed -o <output> [flags] [script-or-url...]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	output := fs.String("o", "", "path of the executable to write (required)")
+	base := fs.String("base", "", "uv-runner executable to embed into, e.g. a uv-runner-gui build (default: this executable)")
+	target := fs.String("target", "", "target triple of the base executable, for the embedded uv (default: this machine)")
+	withUV := fs.Bool("with-uv", true, "embed the uv release archive")
+	fs.Parse(args)
+
+	if *output == "" {
+		fs.Usage()
+		return fmt.Errorf("-o is required")
+	}
+	if *target == "" {
+		detected, err := detectTarget()
+		if err != nil {
+			return err
+		}
+		*target = detected
+	}
+
+	manifest, files, err := collectScripts(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	if *withUV {
+		uv, data, err := fetchUVForPayload(cfg, *target)
+		if err != nil {
+			return err
+		}
+		manifest.UV = uv
+		files[uv.File] = data
+	}
+
+	return writeLauncher(*base, *output, manifest, files)
+}
+
+// collectScripts builds the payload's script list: local files are stored in
+// the payload under scripts/, anything else (URLs, tools) is kept as a
+// reference.
+func collectScripts(scripts []string) (Manifest, map[string][]byte, error) {
+	manifest := Manifest{}
+	files := map[string][]byte{}
+	for _, script := range scripts {
+		if strings.Contains(script, "://") || isToolEntry(script) {
+			manifest.Scripts = append(manifest.Scripts, script)
+			continue
+		}
+		data, err := os.ReadFile(script)
+		if err != nil {
+			return manifest, nil, err
+		}
+		name := path.Join("scripts", filepath.Base(script))
+		if _, dup := files[name]; dup {
+			return manifest, nil, fmt.Errorf("two scripts are named %s", filepath.Base(script))
+		}
+		files[name] = data
+		manifest.Scripts = append(manifest.Scripts, name)
+	}
+	return manifest, files, nil
+}
+
+// fetchUVForPayload downloads and verifies the uv archive for target.
+func fetchUVForPayload(cfg *Config, target string) (*EmbeddedUV, []byte, error) {
+	tmpFile, err := os.CreateTemp("", "uv-*-"+uvArchiveName(target))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	client, err := cfg.httpClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := fetchUVArchive(context.Background(), newDownloader(client, logStdout), cfg, tmpFile, target); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(tmpFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &EmbeddedUV{
+		Version: cfg.uvVersion(),
+		Target:  target,
+		File:    path.Join("uv", uvArchiveName(target)),
+		SHA256:  sha256Hex(data),
+	}, data, nil
+}
+
+// writeLauncher copies base (this executable when empty), minus any payload it
+// already carries, to output and appends a new payload.
+func writeLauncher(base, output string, manifest Manifest, files map[string][]byte) error {
+	if base == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		base = exe
+	}
+	in, err := os.Open(base)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	size, err := executableSize(in)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, io.NewSectionReader(in, 0, size)); err != nil {
+		out.Close()
+		return err
+	}
+	if err := writePayload(out, manifest, files); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s\n", output)
+	return nil
+}
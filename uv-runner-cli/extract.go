@@ -0,0 +1,225 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// maxBinarySize caps the decompressed size of each extracted executable; uv
+// itself is around 50 MB, so anything far larger is not a uv release.
+const maxBinarySize = 256 << 20
+
+// uvArchiveName returns the file name of the uv release archive for target.
+func uvArchiveName(target string) string {
+	if strings.Contains(target, "windows") {
+		return "uv-" + target + ".zip"
+	}
+	return "uv-" + target + ".tar.gz"
+}
+
+// extractUVArchive extracts uv from the verified archive in f into destDir
+// and returns its path.
+func extractUVArchive(f *os.File, destDir, target string) (string, error) {
+	// Reset file position for reading
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to reset file position: %w", err)
+	}
+
+	if strings.HasSuffix(uvArchiveName(target), ".zip") {
+		return extractZip(f, destDir, target)
+	}
+	return extractTarGz(f, destDir, target)
+}
+
+// uvArchiveLayout describes which executables a uv release archive for target
+// contains and where: Unix tarballs nest them under "uv-<target>/", Windows
+// zips keep them at the top level.
+type uvArchiveLayout struct {
+	dir      string
+	required []string
+	optional []string
+}
+
+func archiveLayout(target string) uvArchiveLayout {
+	if strings.Contains(target, "windows") {
+		return uvArchiveLayout{
+			required: []string{"uv.exe"},
+			optional: []string{"uvx.exe", "uvw.exe"},
+		}
+	}
+	return uvArchiveLayout{
+		dir:      "uv-" + target,
+		required: []string{"uv"},
+		optional: []string{"uvx"},
+	}
+}
+
+// match returns the executable name for an archive entry, or "" when the entry
+// is not one we extract. Entries that carry an executable's name at an
+// unexpected location are rejected rather than skipped.
+func (l uvArchiveLayout) match(entry string) (string, error) {
+	name := path.Base(entry)
+	if !slices.Contains(l.required, name) && !slices.Contains(l.optional, name) {
+		return "", nil
+	}
+	clean := path.Clean(strings.TrimPrefix(entry, "./"))
+	if clean != path.Join(l.dir, name) {
+		return "", fmt.Errorf("unexpected archive entry %q (want %q)", entry, path.Join(l.dir, name))
+	}
+	return name, nil
+}
+
+// writeBinary copies at most maxBinarySize bytes from r to a new executable
+// in destDir.
+func writeBinary(r io.Reader, destDir, name string) (string, error) {
+	dest := filepath.Join(destDir, name)
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	n, err := io.Copy(out, io.LimitReader(r, maxBinarySize+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > maxBinarySize {
+		err = fmt.Errorf("%s exceeds the maximum size of %d bytes", name, maxBinarySize)
+	}
+	if err != nil {
+		os.Remove(dest)
+		return "", err
+	}
+	return dest, nil
+}
+
+// extracted tracks which executables an archive yielded.
+type extracted map[string]string
+
+func (e extracted) checkDuplicate(name string) error {
+	if _, dup := e[name]; dup {
+		return fmt.Errorf("archive contains %s more than once", name)
+	}
+	return nil
+}
+
+// uvPath checks that every required executable was found and returns the
+// path of uv itself.
+func (e extracted) uvPath(layout uvArchiveLayout) (string, error) {
+	for _, name := range layout.required {
+		if _, ok := e[name]; !ok {
+			return "", fmt.Errorf("%s binary not found in archive", name)
+		}
+	}
+	return e[layout.required[0]], nil
+}
+
+// extractTarGz extracts the uv executables for target from a tar.gz archive
+// into destDir and returns the path of uv. Only regular files at the
+// expected location are accepted.
+func extractTarGz(r io.Reader, destDir, target string) (string, error) {
+	layout := archiveLayout(target)
+
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return "", err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	found := extracted{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		name, err := layout.match(header.Name)
+		if err != nil {
+			return "", err
+		}
+		if name == "" {
+			continue
+		}
+		// Hard links report a regular file mode, so check the type itself
+		if header.Typeflag != tar.TypeReg {
+			return "", fmt.Errorf("archive entry %q is not a regular file", header.Name)
+		}
+		if header.Size > maxBinarySize {
+			return "", fmt.Errorf("archive entry %q is %d bytes, more than the maximum of %d", header.Name, header.Size, maxBinarySize)
+		}
+
+		if err := found.checkDuplicate(name); err != nil {
+			return "", err
+		}
+
+		dest, err := writeBinary(tr, destDir, name)
+		if err != nil {
+			return "", err
+		}
+		found[name] = dest
+	}
+
+	return found.uvPath(layout)
+}
+
+// extractZip is the zip counterpart of extractTarGz.
+func extractZip(file *os.File, destDir, target string) (string, error) {
+	layout := archiveLayout(target)
+
+	// Get file info for zip reader
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	// Create zip reader
+	zr, err := zip.NewReader(file, fileInfo.Size())
+	if err != nil {
+		return "", err
+	}
+
+	found := extracted{}
+	for _, f := range zr.File {
+		name, err := layout.match(f.Name)
+		if err != nil {
+			return "", err
+		}
+		if name == "" {
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			return "", fmt.Errorf("archive entry %q is not a regular file", f.Name)
+		}
+		if f.UncompressedSize64 > maxBinarySize {
+			return "", fmt.Errorf("archive entry %q is %d bytes, more than the maximum of %d", f.Name, f.UncompressedSize64, maxBinarySize)
+		}
+
+		if err := found.checkDuplicate(name); err != nil {
+			return "", err
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		dest, err := writeBinary(rc, destDir, name)
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+		found[name] = dest
+	}
+
+	return found.uvPath(layout)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	linuxTarget   = "x86_64-unknown-linux-gnu"
+	windowsTarget = "x86_64-pc-windows-msvc"
+)
+
+// archiveMember is an entry of a crafted archive. Size overrides the size in
+// the header without writing the content, for oversized members.
+type archiveMember struct {
+	name     string
+	typeflag byte
+	mode     fs.FileMode
+	body     string
+	size     int64
+}
+
+func buildTarGz(t *testing.T, members []archiveMember) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for _, m := range members {
+		hdr := &tar.Header{Name: m.name, Typeflag: m.typeflag, Mode: 0755, Size: int64(len(m.body))}
+		switch m.typeflag {
+		case tar.TypeSymlink, tar.TypeLink:
+			hdr.Linkname, hdr.Size = m.body, 0
+		}
+		if m.size != 0 {
+			hdr.Size = m.size
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if m.size != 0 {
+			// Leave the archive truncated after the oversized header
+			break
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write([]byte(m.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tw.Flush()
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func buildZipFile(t *testing.T, members []archiveMember) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "uv.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	zw := zip.NewWriter(f)
+	for _, m := range members {
+		hdr := &zip.FileHeader{Name: m.name, Method: zip.Deflate}
+		mode := m.mode
+		if mode == 0 {
+			mode = 0755
+		}
+		hdr.SetMode(mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(m.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestExtractTarGz(t *testing.T) {
+	dir := "uv-" + linuxTarget + "/"
+	tests := []struct {
+		name    string
+		members []archiveMember
+		wantErr string
+	}{
+		{
+			name: "valid archive",
+			members: []archiveMember{
+				{name: dir, typeflag: tar.TypeDir},
+				{name: dir + "uv", typeflag: tar.TypeReg, body: "uv binary"},
+				{name: dir + "uvx", typeflag: tar.TypeReg, body: "uvx binary"},
+				{name: dir + "README.md", typeflag: tar.TypeReg, body: "ignored"},
+			},
+		},
+		{
+			name:    "parent traversal",
+			members: []archiveMember{{name: "../uv", typeflag: tar.TypeReg, body: "evil"}},
+			wantErr: "unexpected archive entry",
+		},
+		{
+			name:    "traversal through the release directory",
+			members: []archiveMember{{name: dir + "../../uv", typeflag: tar.TypeReg, body: "evil"}},
+			wantErr: "unexpected archive entry",
+		},
+		{
+			name:    "absolute path",
+			members: []archiveMember{{name: "/" + dir + "uv", typeflag: tar.TypeReg, body: "evil"}},
+			wantErr: "unexpected archive entry",
+		},
+		{
+			name:    "symlink member",
+			members: []archiveMember{{name: dir + "uv", typeflag: tar.TypeSymlink, body: "/bin/sh"}},
+			wantErr: "not a regular file",
+		},
+		{
+			name: "hardlink member",
+			members: []archiveMember{
+				{name: dir + "uvx", typeflag: tar.TypeReg, body: "uvx binary"},
+				{name: dir + "uv", typeflag: tar.TypeLink, body: "/etc/passwd"},
+			},
+			wantErr: "not a regular file",
+		},
+		{
+			name:    "oversized binary",
+			members: []archiveMember{{name: dir + "uv", typeflag: tar.TypeReg, size: maxBinarySize + 1}},
+			wantErr: "more than the maximum",
+		},
+		{
+			name:    "missing binary",
+			members: []archiveMember{{name: dir + "uvx", typeflag: tar.TypeReg, body: "uvx binary"}},
+			wantErr: "uv binary not found",
+		},
+		{
+			name: "duplicate binary",
+			members: []archiveMember{
+				{name: dir + "uv", typeflag: tar.TypeReg, body: "uv binary"},
+				{name: "./" + dir + "uv", typeflag: tar.TypeReg, body: "evil"},
+			},
+			wantErr: "more than once",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			uvPath, err := extractTarGz(bytes.NewReader(buildTarGz(t, tt.members)), dest, linuxTarget)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("extractTarGz() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractTarGz() = %v", err)
+			}
+			if uvPath != filepath.Join(dest, "uv") {
+				t.Errorf("uv path = %s", uvPath)
+			}
+			entries, err := os.ReadDir(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 {
+				t.Errorf("extracted %v, want uv and uvx only", entries)
+			}
+			if data, _ := os.ReadFile(uvPath); string(data) != "uv binary" {
+				t.Errorf("uv content = %q", data)
+			}
+		})
+	}
+}
+
+func TestExtractZip(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []archiveMember
+		wantErr string
+	}{
+		{
+			name: "valid archive",
+			members: []archiveMember{
+				{name: "uv.exe", body: "uv binary"},
+				{name: "uvx.exe", body: "uvx binary"},
+				{name: "uvw.exe", body: "uvw binary"},
+			},
+		},
+		{
+			name:    "nested binary",
+			members: []archiveMember{{name: "sub/uv.exe", body: "evil"}},
+			wantErr: "unexpected archive entry",
+		},
+		{
+			name:    "parent traversal",
+			members: []archiveMember{{name: "../uv.exe", body: "evil"}},
+			wantErr: "unexpected archive entry",
+		},
+		{
+			name:    "symlink member",
+			members: []archiveMember{{name: "uv.exe", mode: fs.ModeSymlink | 0777, body: "C:/Windows/System32/cmd.exe"}},
+			wantErr: "not a regular file",
+		},
+		{
+			name:    "missing binary",
+			members: []archiveMember{{name: "uvx.exe", body: "uvx binary"}},
+			wantErr: "uv.exe binary not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			uvPath, err := extractZip(buildZipFile(t, tt.members), dest, windowsTarget)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("extractZip() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractZip() = %v", err)
+			}
+			if data, _ := os.ReadFile(uvPath); string(data) != "uv binary" {
+				t.Errorf("uv content = %q", data)
+			}
+		})
+	}
+}
+
+// endlessReader yields zeros forever.
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestWriteBinaryLimitsSize(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes the maximum binary size to disk")
+	}
+	dest := t.TempDir()
+	if _, err := writeBinary(endlessReader{}, dest, "uv"); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("writeBinary() = %v, want a size error", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "uv")); !os.IsNotExist(err) {
+		t.Errorf("oversized binary left behind: %v", err)
+	}
+
+	if _, err := writeBinary(io.LimitReader(endlessReader{}, 1024), dest, "uv"); err != nil {
+		t.Errorf("writeBinary() = %v", err)
+	}
+}
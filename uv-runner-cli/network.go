@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// systemCABundles lists where common Unix distributions keep their CA bundle.
+// When extra certificates are configured, the first bundle found is combined
+// with them for uv, which otherwise would trust only the extra ones.
+var systemCABundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt", // Debian, Ubuntu, Alpine
+	"/etc/pki/tls/certs/ca-bundle.crt",   // Fedora, RHEL
+	"/etc/ssl/ca-bundle.pem",             // openSUSE
+	"/etc/ssl/cert.pem",                  // macOS, BSD
+}
+
+// proxyURL returns the configured proxy with credentials applied, or nil when
+// no explicit proxy is set.
+func (c *Config) proxyURL() (*url.URL, error) {
+	if c.Proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(c.Proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", c.Proxy)
+	}
+	if c.ProxyUser != "" {
+		u.User = url.UserPassword(c.ProxyUser, c.ProxyPassword)
+	}
+	return u, nil
+}
+
+// certPool returns the system roots plus the certificates in CAFile, or nil
+// when no CA file is configured.
+func (c *Config) certPool() (*x509.CertPool, error) {
+	if c.CAFile == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+	}
+	return pool, nil
+}
+
+// httpClient builds the client used for every download uv-runner makes itself.
+func (c *Config) httpClient() (*http.Client, error) {
+	client := newHTTPClient()
+	transport := client.Transport.(*http.Transport)
+
+	proxy, err := c.proxyURL()
+	if err != nil {
+		return nil, err
+	}
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	pool, err := c.certPool()
+	if err != nil {
+		return nil, err
+	}
+	if pool != nil {
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return client, nil
+}
+
+// uvEnv returns the environment variables that make uv use the same proxy
+// and CA certificates, the configured package indexes and the profile's uv
+// cache. Files it needs are written to workDir.
+func (c *Config) uvEnv(workDir string) ([]string, error) {
+	var env []string
+
+	proxy, err := c.proxyURL()
+	if err != nil {
+		return nil, err
+	}
+	if proxy != nil {
+		env = append(env,
+			"HTTP_PROXY="+proxy.String(),
+			"HTTPS_PROXY="+proxy.String(),
+			"ALL_PROXY="+proxy.String(),
+		)
+	}
+
+	if c.CAFile != "" {
+		bundle, err := c.writeCABundle(workDir)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, "SSL_CERT_FILE="+bundle)
+	}
+
+	indexes, err := c.indexEnv()
+	if err != nil {
+		return nil, err
+	}
+	env = append(env, indexes...)
+
+	profile, err := c.profileEnv()
+	if err != nil {
+		return nil, err
+	}
+	env = append(env, profile...)
+
+	return env, nil
+}
+
+// writeCABundle combines the system CA bundle, where one can be found, with
+// CAFile so that uv trusts both.
+func (c *Config) writeCABundle(workDir string) (string, error) {
+	extra, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read CA file: %w", err)
+	}
+
+	var bundle []byte
+	for _, path := range systemCABundles {
+		if data, err := os.ReadFile(path); err == nil {
+			bundle = append(data, '\n')
+			break
+		}
+	}
+	bundle = append(bundle, extra...)
+
+	path := filepath.Join(workDir, "ca-bundle.pem")
+	if err := os.WriteFile(path, bundle, 0644); err != nil {
+		return "", fmt.Errorf("failed to write CA bundle: %w", err)
+	}
+	return path, nil
+}
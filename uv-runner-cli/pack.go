@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Project is the input to `uv-runner-cli pack`. It mirrors Manifest, but
+// script and icon paths refer to the packing machine and are resolved
+// relative to the project file.
+type Project struct {
+	Name      string            `json:"name"`
+	Icon      string            `json:"icon,omitempty"`
+	Scripts   []string          `json:"scripts"`
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	UVVersion string            `json:"uv_version,omitempty"`
+
+	// Index and ExtraIndexes are the package indexes the launcher's scripts
+	// install from. Credentials must not be part of the URLs, as anyone
+	// with the launcher could read them.
+	Index        string   `json:"index,omitempty"`
+	ExtraIndexes []string `json:"extra_indexes,omitempty"`
+
+	// EmbedUV includes the uv archive so the launcher works offline; it
+	// defaults to true.
+	EmbedUV *bool `json:"embed_uv,omitempty"`
+
+	// Base is the uv-runner executable to brand, typically a uv-runner-gui
+	// build; Target is its platform when that differs from this machine.
+	Base   string `json:"base,omitempty"`
+	Target string `json:"target,omitempty"`
+}
+
+func loadProject(file string) (*Project, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	project := &Project{}
+	if err := json.Unmarshal(data, project); err != nil {
+		return nil, fmt.Errorf("failed to parse project %s: %w", file, err)
+	}
+	if project.Name == "" {
+		return nil, fmt.Errorf("project %s has no name", file)
+	}
+	if len(project.Scripts) == 0 {
+		return nil, fmt.Errorf("project %s has no scripts", file)
+	}
+
+	// Resolve local paths against the project's directory
+	dir := filepath.Dir(file)
+	resolve := func(p string) string {
+		if p == "" || strings.Contains(p, "://") || isToolEntry(p) || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	for i, script := range project.Scripts {
+		project.Scripts[i] = resolve(script)
+	}
+	project.Icon = resolve(project.Icon)
+
+	for _, index := range append([]string{project.Index}, project.ExtraIndexes...) {
+		if u, err := url.Parse(index); err == nil && u.User != nil {
+			return nil, fmt.Errorf("index URL %s in project %s contains credentials; set them in index_credentials of the user config instead", redact(index), file)
+		}
+	}
+	project.Base = resolve(project.Base)
+
+	return project, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// launcherName derives an output file name from the project name.
+func launcherName(name, target string) string {
+	file := strings.Trim(unsafeFileChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if file == "" {
+		file = "launcher"
+	}
+	if strings.Contains(target, "windows") {
+		file += ".exe"
+	}
+	return file
+}
+
+// runPack implements `uv-runner-cli pack`, which writes a branded launcher
+// with a project's configuration embedded.
+func runPack(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("pack", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s pack [flags] <project.json>\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	output := fs.String("o", "", "path of the launcher to write (default: derived from the project name)")
+	base := fs.String("base", "", "uv-runner executable to brand (overrides the project's base)")
+	target := fs.String("target", "", "target triple of the base executable (overrides the project's target)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one project file")
+	}
+	project, err := loadProject(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *base != "" {
+		project.Base = *base
+	}
+	if *target != "" {
+		project.Target = *target
+	}
+	if project.Target == "" {
+		project.Target, err = detectTarget()
+		if err != nil {
+			return err
+		}
+	}
+	if *output == "" {
+		*output = launcherName(project.Name, project.Target)
+	}
+
+	manifest, files, err := collectScripts(project.Scripts)
+	if err != nil {
+		return err
+	}
+	manifest.Name = project.Name
+	manifest.Args = project.Args
+	manifest.Env = project.Env
+	manifest.UVVersion = project.UVVersion
+	manifest.Index = project.Index
+	manifest.ExtraIndexes = project.ExtraIndexes
+
+	if project.Icon != "" {
+		data, err := os.ReadFile(project.Icon)
+		if err != nil {
+			return fmt.Errorf("failed to read icon: %w", err)
+		}
+		manifest.Icon = path.Join("assets", "icon"+filepath.Ext(project.Icon))
+		files[manifest.Icon] = data
+	}
+
+	if project.EmbedUV == nil || *project.EmbedUV {
+		manifest.applyTo(cfg)
+		uv, data, err := fetchUVForPayload(cfg, project.Target)
+		if err != nil {
+			return err
+		}
+		manifest.UV = uv
+		files[uv.File] = data
+	}
+
+	return writeLauncher(project.Base, *output, manifest, files)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+)
+
+// hostProbe gathers what target detection needs to know about the machine.
+// The file system hooks let the libc and CPU checks run against fake inputs.
+type hostProbe struct {
+	goos     string
+	goarch   string
+	glob     func(pattern string) ([]string, error)
+	readFile func(name string) ([]byte, error)
+}
+
+func currentHost() hostProbe {
+	return hostProbe{
+		goos:     runtime.GOOS,
+		goarch:   runtime.GOARCH,
+		glob:     filepath.Glob,
+		readFile: os.ReadFile,
+	}
+}
+
+// detectTarget returns the uv release target triple for this machine.
+func detectTarget() (string, error) {
+	return currentHost().target()
+}
+
+// target maps the host to one of the target triples uv publishes builds for.
+func (h hostProbe) target() (string, error) {
+	switch h.goos {
+	case "darwin":
+		switch h.goarch {
+		case "amd64":
+			return "x86_64-apple-darwin", nil
+		case "arm64":
+			return "aarch64-apple-darwin", nil
+		}
+	case "windows":
+		switch h.goarch {
+		case "amd64":
+			return "x86_64-pc-windows-msvc", nil
+		case "arm64":
+			return "aarch64-pc-windows-msvc", nil
+		case "386":
+			return "i686-pc-windows-msvc", nil
+		}
+	case "linux":
+		return h.linuxTarget()
+	default:
+		return "", fmt.Errorf("unsupported platform: %s", h.goos)
+	}
+	return "", fmt.Errorf("unsupported architecture %s on %s", h.goarch, h.goos)
+}
+
+func (h hostProbe) linuxTarget() (string, error) {
+	musl := h.isMusl()
+
+	// uv ships both glibc and static musl builds for these; the musl build
+	// also serves hosts where no dynamic loader could be found.
+	libc := "gnu"
+	if musl {
+		libc = "musl"
+	}
+	switch h.goarch {
+	case "amd64":
+		return "x86_64-unknown-linux-" + libc, nil
+	case "arm64":
+		return "aarch64-unknown-linux-" + libc, nil
+	case "386":
+		return "i686-unknown-linux-" + libc, nil
+	case "arm":
+		if h.armVersion() < 7 {
+			return "arm-unknown-linux-musleabihf", nil
+		}
+		return "armv7-unknown-linux-" + libc + "eabihf", nil
+	}
+
+	// These are only published for glibc.
+	var target string
+	switch h.goarch {
+	case "ppc64le":
+		target = "powerpc64le-unknown-linux-gnu"
+	case "ppc64":
+		target = "powerpc64-unknown-linux-gnu"
+	case "s390x":
+		target = "s390x-unknown-linux-gnu"
+	case "riscv64":
+		target = "riscv64gc-unknown-linux-gnu"
+	default:
+		return "", fmt.Errorf("unsupported architecture %s on linux", h.goarch)
+	}
+	if musl {
+		return "", fmt.Errorf("uv does not publish musl builds for %s", h.goarch)
+	}
+	return target, nil
+}
+
+// isMusl reports whether the host's C library is musl, judged by which
+// dynamic loader is installed. Hosts with neither loader (e.g. distroless
+// containers) count as musl, since uv's musl builds are statically linked.
+func (h hostProbe) isMusl() bool {
+	if matches, _ := h.glob("/lib/ld-musl-*.so.1"); len(matches) > 0 {
+		return true
+	}
+	for _, pattern := range []string{"/lib*/ld-linux*.so.*", "/lib/*/ld-linux*.so.*", "/lib*/ld64.so.*"} {
+		if matches, _ := h.glob(pattern); len(matches) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+var cpuArchitecture = regexp.MustCompile(`(?m)^CPU architecture\s*:\s*(\d+)`)
+
+// armVersion returns the ARM architecture version of the CPU, falling back to
+// the GOARM the binary was built for when /proc/cpuinfo is unavailable.
+func (h hostProbe) armVersion() int {
+	if data, err := h.readFile("/proc/cpuinfo"); err == nil {
+		if m := cpuArchitecture.FindSubmatch(data); m != nil {
+			if v, err := strconv.Atoi(string(m[1])); err == nil {
+				return v
+			}
+		}
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "GOARM" && len(s.Value) > 0 {
+				if v, err := strconv.Atoi(s.Value[:1]); err == nil {
+					return v
+				}
+			}
+		}
+	}
+	return 7
+}
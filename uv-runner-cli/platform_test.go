@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+// fakeHost returns a probe for a Linux machine with the given files, which
+// are matched by glob and returned by readFile.
+func fakeHost(goarch string, files map[string]string) hostProbe {
+	return hostProbe{
+		goos:   "linux",
+		goarch: goarch,
+		glob: func(pattern string) ([]string, error) {
+			var matches []string
+			for name := range files {
+				if ok, err := path.Match(pattern, name); err != nil {
+					return nil, err
+				} else if ok {
+					matches = append(matches, name)
+				}
+			}
+			return matches, nil
+		},
+		readFile: func(name string) ([]byte, error) {
+			if content, ok := files[name]; ok {
+				return []byte(content), nil
+			}
+			return nil, os.ErrNotExist
+		},
+	}
+}
+
+var (
+	glibcLoader   = map[string]string{"/lib64/ld-linux-x86-64.so.2": ""}
+	multiarchGlib = map[string]string{"/lib/aarch64-linux-gnu/ld-linux-aarch64.so.1": ""}
+	ppcLoader     = map[string]string{"/lib64/ld64.so.2": ""}
+	muslLoader    = map[string]string{"/lib/ld-musl-x86_64.so.1": ""}
+)
+
+func TestIsMusl(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  bool
+	}{
+		{name: "glibc", files: glibcLoader, want: false},
+		{name: "glibc multiarch", files: multiarchGlib, want: false},
+		{name: "glibc ppc64", files: ppcLoader, want: false},
+		{name: "musl", files: muslLoader, want: true},
+		{name: "both loaders", files: map[string]string{"/lib/ld-musl-x86_64.so.1": "", "/lib64/ld-linux-x86-64.so.2": ""}, want: true},
+		{name: "no loader", files: nil, want: true},
+	}
+	for _, tt := range tests {
+		if got := fakeHost("amd64", tt.files).isMusl(); got != tt.want {
+			t.Errorf("%s: isMusl() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestArmVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		cpuinfo *string
+		want    int
+	}{
+		{name: "ARMv6", cpuinfo: ptr("processor\t: 0\nCPU architecture: 6\n"), want: 6},
+		{name: "ARMv7", cpuinfo: ptr("processor\t: 0\nCPU architecture: 7\nCPU variant\t: 0x0\n"), want: 7},
+		{name: "ARMv8 in 32-bit mode", cpuinfo: ptr("CPU architecture : 8\n"), want: 8},
+		{name: "no architecture line", cpuinfo: ptr("processor\t: 0\n"), want: 7},
+		{name: "no cpuinfo", want: 7},
+	}
+	for _, tt := range tests {
+		files := map[string]string{}
+		if tt.cpuinfo != nil {
+			files["/proc/cpuinfo"] = *tt.cpuinfo
+		}
+		// Without cpuinfo the test binary's own GOARM, unset off ARM,
+		// leaves the default of 7
+		if got := fakeHost("arm", files).armVersion(); got != tt.want {
+			t.Errorf("%s: armVersion() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLinuxTarget(t *testing.T) {
+	armv6 := map[string]string{"/proc/cpuinfo": "CPU architecture: 6\n", "/lib/ld-linux-armhf.so.3": ""}
+	armv7 := map[string]string{"/proc/cpuinfo": "CPU architecture: 7\n", "/lib/ld-linux-armhf.so.3": ""}
+	armv7Musl := map[string]string{"/proc/cpuinfo": "CPU architecture: 7\n", "/lib/ld-musl-armhf.so.1": ""}
+
+	tests := []struct {
+		goarch  string
+		files   map[string]string
+		want    string
+		wantErr bool
+	}{
+		{goarch: "amd64", files: glibcLoader, want: "x86_64-unknown-linux-gnu"},
+		{goarch: "amd64", files: muslLoader, want: "x86_64-unknown-linux-musl"},
+		{goarch: "amd64", files: nil, want: "x86_64-unknown-linux-musl"},
+		{goarch: "arm64", files: multiarchGlib, want: "aarch64-unknown-linux-gnu"},
+		{goarch: "386", files: map[string]string{"/lib/ld-linux.so.2": ""}, want: "i686-unknown-linux-gnu"},
+		{goarch: "arm", files: armv6, want: "arm-unknown-linux-musleabihf"},
+		{goarch: "arm", files: armv7, want: "armv7-unknown-linux-gnueabihf"},
+		{goarch: "arm", files: armv7Musl, want: "armv7-unknown-linux-musleabihf"},
+		{goarch: "ppc64le", files: ppcLoader, want: "powerpc64le-unknown-linux-gnu"},
+		{goarch: "s390x", files: map[string]string{"/lib/ld64.so.1": ""}, want: "s390x-unknown-linux-gnu"},
+		{goarch: "riscv64", files: map[string]string{"/lib/ld-linux-riscv64-lp64d.so.1": ""}, want: "riscv64gc-unknown-linux-gnu"},
+		{goarch: "ppc64le", files: muslLoader, wantErr: true},
+		{goarch: "mips", files: glibcLoader, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := fakeHost(tt.goarch, tt.files).target()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s with %v: target() = %q, %v; want %q, error %v", tt.goarch, tt.files, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
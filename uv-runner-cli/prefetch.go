@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// logWriter passes output written to it on to a logFunc.
+type logWriter logFunc
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w("%s", p)
+	return len(p), nil
+}
+
+// prepareScripts resolves the dependencies of each script and installs them
+// into uv's cache with `uv sync --script`, so that later runs start quickly
+// and work offline. Remote scripts are fetched and checked as for running
+// them. It reports on each script and returns how many failed.
+func prepareScripts(ctx context.Context, dl *downloader, cfg *Config, uvPath, tempDir string, scripts []string, approve approveFunc, logf logFunc) (failed int) {
+	env, err := cfg.uvEnv(tempDir)
+	if err != nil {
+		logf("Error configuring uv environment: %v\n", err)
+		return len(scripts)
+	}
+	env = append(os.Environ(), env...)
+
+	for i, ref := range scripts {
+		if err := prepareScript(ctx, dl, cfg, uvPath, env, ref, filepath.Join(tempDir, "prepare", strconv.Itoa(i)), approve, logf); err != nil {
+			logf("Could not prepare %s: %v\n", ref, err)
+			failed++
+		}
+	}
+	logf("Prepared %d of %d scripts\n", len(scripts)-failed, len(scripts))
+	return failed
+}
+
+func prepareScript(ctx context.Context, dl *downloader, cfg *Config, uvPath string, env []string, ref, dir string, approve approveFunc, logf logFunc) error {
+	if isToolEntry(ref) {
+		logf("Nothing to prepare for %s: uv sets up tools when they run\n", ref)
+		return nil
+	}
+	local, err := localScripts(ctx, dl, cfg, []string{ref}, dir, approve)
+	if err != nil {
+		return err
+	}
+	source, err := os.ReadFile(local[0])
+	if err != nil {
+		return err
+	}
+	metadata, err := parseScriptMetadata(source)
+	if err != nil {
+		return err
+	}
+	if metadata == nil {
+		logf("Nothing to prepare for %s: it declares no inline dependencies\n", ref)
+		return nil
+	}
+	args := []string{"sync", "--script", local[0]}
+	if python := cfg.python(ref); python != "" {
+		if err := checkRequiresPython(metadata.RequiresPython, python); err != nil {
+			return err
+		}
+		args = append(args, "--python", python)
+	}
+
+	logf("Preparing %s...\n", ref)
+	cmd := exec.CommandContext(ctx, uvPath, args...)
+	cmd.Env = env
+	cmd.Stdout = logWriter(logf)
+	cmd.Stderr = logWriter(logf)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("uv sync failed: %w", err)
+	}
+	logf("Prepared %s\n", ref)
+	return nil
+}
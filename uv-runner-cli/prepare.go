@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runPrepare implements `uv-runner-cli prepare`, which resolves and caches
+// the dependencies of scripts ahead of time so that they start quickly and
+// can run offline.
+func runPrepare(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("prepare", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s prepare [flags] [script-or-url...]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	approveScripts := fs.Bool("approve-scripts", false, "prepare remote scripts that are new or changed since they were last approved")
+	fs.Parse(args)
+
+	embedded, err := openPayload()
+	if err != nil {
+		return fmt.Errorf("failed to read embedded payload: %w", err)
+	}
+
+	tempDir, err := makeRuntimeDir(cfg, logStdout)
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	uvPath, err := setUpUV(cfg, embedded, tempDir)
+	if err != nil {
+		return err
+	}
+	scripts, _, err := chooseScripts(embedded, fs.Args(), tempDir)
+	if err != nil {
+		return fmt.Errorf("failed to extract embedded scripts: %w", err)
+	}
+
+	client, err := cfg.httpClient()
+	if err != nil {
+		return err
+	}
+	dl := newDownloader(client, logStdout)
+	if failed := prepareScripts(context.Background(), dl, cfg, uvPath, tempDir, scripts, approveFlag(*approveScripts), logStdout); failed > 0 {
+		return fmt.Errorf("%d of %d scripts could not be prepared", failed, len(scripts))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runPS implements `uv-runner-cli ps`, which lists or stops processes started
+// by uv-runner sessions that are no longer running.
+func runPS(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("ps", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s ps [stop]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(fs.Output(), "  (none)  list processes left running by earlier uv-runner sessions")
+		fmt.Fprintln(fs.Output(), "  stop    terminate them")
+	}
+	fs.Parse(args)
+
+	survivors, err := survivingProcesses(false)
+	if err != nil {
+		return err
+	}
+
+	switch fs.Arg(0) {
+	case "":
+		if len(survivors) == 0 {
+			fmt.Println("No processes from earlier sessions are running")
+		}
+		for _, r := range survivors {
+			fmt.Println(r)
+		}
+		return nil
+	case "stop":
+		failed := 0
+		for _, r := range survivors {
+			if err := r.stop(); err != nil {
+				fmt.Printf("Could not stop PID %d: %v\n", r.PID, err)
+				failed++
+				continue
+			}
+			fmt.Printf("Stopped PID %d\n", r.PID)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d process(es) could not be stopped", failed)
+		}
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown ps command %q", fs.Arg(0))
+	}
+}
+
+// warnSurvivors points out servers that an earlier session left running,
+// which commonly hold on to the ports the new ones need.
+func warnSurvivors() {
+	survivors, err := survivingProcesses(true)
+	if err != nil {
+		fmt.Printf("Error checking for processes from earlier sessions: %v\n", err)
+		return
+	}
+	if len(survivors) == 0 {
+		return
+	}
+	fmt.Println("Processes started by an earlier uv-runner session are still running:")
+	for _, r := range survivors {
+		fmt.Printf("  %s\n", r)
+	}
+	fmt.Printf("Run `%s ps stop` to terminate them.\n", filepath.Base(os.Args[0]))
+}
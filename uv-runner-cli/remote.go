@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pinFragment marks a SHA-256 pinned in a script URL, as in
+// "https://example.com/main.py#sha256=<hex>".
+const pinFragment = "sha256="
+
+// isRemoteScript reports whether a script reference is fetched by uv-runner
+// rather than passed to uv as is.
+func isRemoteScript(ref string) bool {
+	return strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://")
+}
+
+// readScript returns the source of a script without running it, fetching it
+// if it is remote.
+func readScript(ctx context.Context, dl *downloader, ref string) ([]byte, error) {
+	if isRemoteScript(ref) {
+		scriptURL, _ := splitPin(ref)
+		return dl.fetchBytes(ctx, scriptURL)
+	}
+	return os.ReadFile(ref)
+}
+
+// splitPin separates a "#sha256=" pin from a script reference.
+func splitPin(ref string) (string, string) {
+	if i := strings.LastIndex(ref, "#"+pinFragment); i >= 0 {
+		return ref[:i], strings.ToLower(ref[i+1+len(pinFragment):])
+	}
+	return ref, ""
+}
+
+// remoteScript is the content of a remote script as fetched.
+type remoteScript struct {
+	URL    string
+	Data   []byte
+	SHA256 string
+	Pinned bool
+}
+
+// fetchRemoteScript downloads a remote script and checks it against its pinned
+// SHA-256, from the reference itself or from the script's settings.
+func fetchRemoteScript(ctx context.Context, dl *downloader, cfg *Config, ref string) (*remoteScript, error) {
+	scriptURL, pinned := splitPin(ref)
+	if pinned == "" {
+		pinned = strings.ToLower(cfg.script(ref).SHA256)
+	}
+
+	data, err := dl.fetchBytes(ctx, scriptURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch script %s: %w", scriptURL, err)
+	}
+	script := &remoteScript{URL: scriptURL, Data: data, SHA256: sha256Hex(data)}
+	if pinned != "" {
+		if script.SHA256 != pinned {
+			return nil, fmt.Errorf("script %s has SHA-256 %s but %s is pinned; refusing to run it", scriptURL, script.SHA256, pinned)
+		}
+		dl.logf("Verified %s against its pinned SHA-256\n", scriptURL)
+		script.Pinned = true
+	}
+	return script, nil
+}
+
+// save writes the script to dir under its original file name, so uv sees the
+// same name and extension.
+func (s *remoteScript) save(dir string) (string, error) {
+	name := "script.py"
+	if u, err := url.Parse(s.URL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		name = path.Base(u.Path)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, s.Data, 0644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// approveFunc asks the user whether to run a remote script that has not been
+// approved in its current form.
+type approveFunc func(review *scriptReview) bool
+
+// localScripts replaces the remote scripts among scripts with verified copies
+// in dir, so that exactly what was checked is run. Scripts without a pinned
+// digest must have been approved before in their current form, or be
+// approved now.
+func localScripts(ctx context.Context, dl *downloader, cfg *Config, scripts []string, dir string, approve approveFunc) ([]string, error) {
+	var trust *trustStore
+	local := make([]string, len(scripts))
+	for i, ref := range scripts {
+		if !isRemoteScript(ref) {
+			local[i] = ref
+			continue
+		}
+		script, err := fetchRemoteScript(ctx, dl, cfg, ref)
+		if err != nil {
+			return nil, err
+		}
+		if !script.Pinned {
+			if trust == nil {
+				if trust, err = loadTrustStore(); err != nil {
+					return nil, fmt.Errorf("failed to load approved scripts: %w", err)
+				}
+			}
+			if review := trust.review(script); review != nil {
+				if !approve(review) {
+					return nil, fmt.Errorf("script %s was not approved", script.URL)
+				}
+				if err := trust.approve(script); err != nil {
+					return nil, fmt.Errorf("failed to record approval of %s: %w", script.URL, err)
+				}
+			}
+		}
+		local[i], err = script.save(filepath.Join(dir, "remote", strconv.Itoa(i)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return local, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed major.minor.patch version with an optional pre-release
+// suffix, enough to compare uv releases.
+type semver struct {
+	major, minor, patch int
+	pre                 string
+}
+
+func parseSemver(s string) (semver, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+	core, pre, _ := strings.Cut(s, "-")
+
+	parts := strings.Split(core, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return semver{}, fmt.Errorf("invalid version %q", s)
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return semver{}, fmt.Errorf("invalid version %q", s)
+		}
+		nums[i] = n
+	}
+	return semver{major: nums[0], minor: nums[1], patch: nums[2], pre: pre}, nil
+}
+
+func (v semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+	if v.pre != "" {
+		s += "-" + v.pre
+	}
+	return s
+}
+
+// compare returns -1, 0 or 1. A pre-release sorts before its release.
+func (v semver) compare(o semver) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case o.pre == "":
+		return -1
+	}
+	return comparePrerelease(v.pre, o.pre)
+}
+
+// comparePrerelease orders pre-release suffixes as SemVer does: identifier by
+// identifier, numeric ones numerically and below alphanumeric ones, and a
+// suffix that is a prefix of the other first, so that
+// alpha < alpha.1 < alpha.beta < beta.2 < beta.11 < rc.1.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, y := as[i], bs[i]
+		xn, xErr := strconv.ParseUint(x, 10, 64)
+		yn, yErr := strconv.ParseUint(y, 10, 64)
+		switch {
+		case xErr == nil && yErr == nil:
+			if xn != yn {
+				return cmp.Compare(xn, yn)
+			}
+		case xErr == nil:
+			return -1
+		case yErr == nil:
+			return 1
+		case x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// versionConstraint is a comma-separated list of comparisons that must all
+// hold, e.g. ">=0.8, <1.0". Each comparison is one of =, ==, !=, <, <=, >,
+// >=, ~ (same minor) or ^ (same major, or same minor below 1.0).
+type versionConstraint []func(semver) bool
+
+func parseConstraint(s string) (versionConstraint, error) {
+	var c versionConstraint
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" || term == "*" {
+			continue
+		}
+
+		op := ""
+		for _, candidate := range []string{">=", "<=", "==", "!=", ">", "<", "=", "~", "^"} {
+			if strings.HasPrefix(term, candidate) {
+				op = candidate
+				break
+			}
+		}
+		want, err := parseSemver(strings.TrimPrefix(term, op))
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %q: %w", term, err)
+		}
+
+		var check func(semver) bool
+		switch op {
+		case "", "=", "==":
+			check = func(v semver) bool { return v.compare(want) == 0 }
+		case "!=":
+			check = func(v semver) bool { return v.compare(want) != 0 }
+		case ">":
+			check = func(v semver) bool { return v.compare(want) > 0 }
+		case ">=":
+			check = func(v semver) bool { return v.compare(want) >= 0 }
+		case "<":
+			check = func(v semver) bool { return v.compare(want) < 0 }
+		case "<=":
+			check = func(v semver) bool { return v.compare(want) <= 0 }
+		case "~":
+			check = func(v semver) bool {
+				return v.compare(want) >= 0 && v.major == want.major && v.minor == want.minor
+			}
+		case "^":
+			check = func(v semver) bool {
+				if v.compare(want) < 0 || v.major != want.major {
+					return false
+				}
+				return want.major > 0 || v.minor == want.minor
+			}
+		}
+		c = append(c, check)
+	}
+	return c, nil
+}
+
+func (c versionConstraint) allows(v semver) bool {
+	for _, check := range c {
+		if !check(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package main
+
+import "testing"
+
+func TestSemverCompare(t *testing.T) {
+	// Each version sorts before the next, as in the SemVer specification
+	ordered := []string{
+		"0.9.0",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.10.0",
+	}
+	for i, a := range ordered {
+		va, err := parseSemver(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, b := range ordered {
+			vb, err := parseSemver(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := va.compare(vb); got != want {
+				t.Errorf("%s compared to %s = %d, want %d", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestParseSemverIgnoresBuildMetadata(t *testing.T) {
+	a, err := parseSemver("1.0.0-rc.1+build.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := parseSemver("1.0.0-rc.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.compare(b) != 0 {
+		t.Errorf("%s and %s differ", a, b)
+	}
+}
+
+func TestConstraintAllows(t *testing.T) {
+	tests := []struct {
+		constraint, version string
+		want                bool
+	}{
+		{">=0.8, <1.0", "0.9.5", true},
+		{">=0.8, <1.0", "1.0.0", false},
+		{">=1.0.0", "1.0.0-rc.1", false},
+		{"<1.0.0", "1.0.0-rc.1", true},
+		{"~0.9.2", "0.9.7", true},
+		{"~0.9.2", "0.10.0", false},
+		{"^0.9.2", "0.9.9", true},
+		{"^0.9.2", "0.10.0", false},
+		{"^1.2", "1.9.0", true},
+		{"!=0.9.5", "0.9.5", false},
+		{"*", "0.1.0", true},
+	}
+	for _, tt := range tests {
+		c, err := parseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := parseSemver(tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.allows(v); got != tt.want {
+			t.Errorf("%q allows %s = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
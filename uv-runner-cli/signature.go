@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// Signature verification modes.
+const (
+	signatureOff      = "off"
+	signatureOptional = "optional"
+	signatureRequired = "required"
+)
+
+// SignatureConfig describes a signed checksum manifest for uv releases: a
+// sha256sum-style file listing the archives of a release, and a detached
+// Ed25519 signature over it made with one of PublicKeys.
+type SignatureConfig struct {
+	// Mode is "off" (the default), "optional" to verify when possible and
+	// warn otherwise, or "required" to refuse unverified archives.
+	Mode string `json:"mode,omitempty"`
+
+	// ManifestURL is the manifest location; "{version}" is replaced with the
+	// uv version. SignatureURL defaults to ManifestURL + ".sig".
+	ManifestURL  string `json:"manifest_url,omitempty"`
+	SignatureURL string `json:"signature_url,omitempty"`
+
+	// PublicKeys are the trusted Ed25519 keys, either base64 encoded raw
+	// 32-byte keys or PEM "PUBLIC KEY" blocks.
+	PublicKeys []string `json:"public_keys,omitempty"`
+}
+
+func (s *SignatureConfig) mode() (string, error) {
+	switch s.Mode {
+	case "", signatureOff:
+		return signatureOff, nil
+	case signatureOptional, signatureRequired:
+		return s.Mode, nil
+	default:
+		return "", fmt.Errorf("invalid signature mode %q (want off, optional or required)", s.Mode)
+	}
+}
+
+// parsePublicKey accepts a base64 raw Ed25519 key or a PEM encoded one.
+func parsePublicKey(text string) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode([]byte(text)); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key is %T, not Ed25519", key)
+		}
+		return edKey, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key is %d bytes, want %d", len(raw), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// decodeSignature accepts a raw 64-byte signature or its base64 encoding.
+func decodeSignature(data []byte) ([]byte, error) {
+	if len(data) == ed25519.SignatureSize {
+		return data, nil
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("signature is neither a raw nor a base64 encoded Ed25519 signature")
+	}
+	return sig, nil
+}
+
+// verifyManifest checks that manifest is signed by one of keys and lists
+// checksum for archiveName.
+func verifyManifest(manifest, sigData []byte, keys []string, archiveName, checksum string) error {
+	sig, err := decodeSignature(sigData)
+	if err != nil {
+		return err
+	}
+
+	verified := false
+	for i, text := range keys {
+		key, err := parsePublicKey(text)
+		if err != nil {
+			return fmt.Errorf("invalid public key #%d: %w", i+1, err)
+		}
+		if ed25519.Verify(key, manifest, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return fmt.Errorf("manifest signature does not match any trusted public key")
+	}
+
+	// Lines look like "<sha256>  <name>" or "<sha256> *<name>"
+	for _, line := range strings.Split(string(manifest), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != archiveName {
+			continue
+		}
+		if !strings.EqualFold(fields[0], checksum) {
+			return fmt.Errorf("signed manifest lists %s for %s, got %s", fields[0], archiveName, checksum)
+		}
+		return nil
+	}
+	return fmt.Errorf("signed manifest has no entry for %s", archiveName)
+}
+
+// verifySignature checks the provenance of a downloaded archive against the
+// signed manifest according to the configured mode.
+func verifySignature(ctx context.Context, dl *downloader, cfg *SignatureConfig, version, archiveName, checksum string) error {
+	mode, err := cfg.mode()
+	if err != nil || mode == signatureOff {
+		return err
+	}
+
+	err = func() error {
+		if cfg.ManifestURL == "" || len(cfg.PublicKeys) == 0 {
+			return fmt.Errorf("signature verification needs manifest_url and public_keys")
+		}
+		manifestURL := strings.ReplaceAll(cfg.ManifestURL, "{version}", version)
+		signatureURL := strings.ReplaceAll(cfg.SignatureURL, "{version}", version)
+		if signatureURL == "" {
+			signatureURL = manifestURL + ".sig"
+		}
+
+		manifest, err := dl.fetchBytes(ctx, manifestURL)
+		if err != nil {
+			return fmt.Errorf("failed to download signed manifest: %w", err)
+		}
+		sig, err := dl.fetchBytes(ctx, signatureURL)
+		if err != nil {
+			return fmt.Errorf("failed to download manifest signature: %w", err)
+		}
+		return verifyManifest(manifest, sig, cfg.PublicKeys, archiveName, checksum)
+	}()
+
+	switch {
+	case err == nil:
+		dl.logf("Signature verification successful\n")
+		return nil
+	case mode == signatureRequired:
+		return fmt.Errorf("signature verification failed: %w", err)
+	default:
+		dl.logf("Warning: signature verification failed, continuing: %v\n", err)
+		return nil
+	}
+}
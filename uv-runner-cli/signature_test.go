@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const (
+		version  = "0.9.9"
+		archive  = "uv-x86_64-unknown-linux-gnu.tar.gz"
+		checksum = "a3c5e8f0b1d2c4e6f8a0b2c4d6e8f0a1b3c5d7e9f1a2b4c6d8e0f2a4b6c8d0e2"
+	)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawKey := base64.StdEncoding.EncodeToString(pub)
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	manifestFor := func(digest string) []byte {
+		return fmt.Appendf(nil, "%s  %s\n%s *uv-aarch64-apple-darwin.tar.gz\n", digest, archive, strings.Repeat("1", 64))
+	}
+
+	cases := []struct {
+		name     string
+		manifest []byte // nil serves 404
+		sig      func(manifest []byte) []byte
+		keys     []string
+		ok       bool
+	}{
+		{
+			name:     "good signature",
+			manifest: manifestFor(checksum),
+			sig:      func(m []byte) []byte { return ed25519.Sign(priv, m) },
+			keys:     []string{rawKey},
+			ok:       true,
+		},
+		{
+			name:     "good base64 signature with PEM key",
+			manifest: manifestFor(checksum),
+			sig: func(m []byte) []byte {
+				return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, m)) + "\n")
+			},
+			keys: []string{pemKey},
+			ok:   true,
+		},
+		{
+			name:     "signed by an untrusted key",
+			manifest: manifestFor(checksum),
+			sig:      func(m []byte) []byte { return ed25519.Sign(otherPriv, m) },
+			keys:     []string{rawKey},
+		},
+		{
+			name:     "tampered manifest",
+			manifest: manifestFor(checksum),
+			sig: func(m []byte) []byte {
+				return ed25519.Sign(priv, manifestFor(strings.Repeat("2", 64)))
+			},
+			keys: []string{rawKey},
+		},
+		{
+			name: "missing manifest",
+			sig:  func(m []byte) []byte { return nil },
+			keys: []string{rawKey},
+		},
+		{
+			name:     "archive listed with the wrong digest",
+			manifest: manifestFor(strings.Repeat("f", 64)),
+			sig:      func(m []byte) []byte { return ed25519.Sign(priv, m) },
+			keys:     []string{rawKey},
+		},
+		{
+			name:     "archive not listed",
+			manifest: []byte(strings.Repeat("1", 64) + "  uv-other.tar.gz\n"),
+			sig:      func(m []byte) []byte { return ed25519.Sign(priv, m) },
+			keys:     []string{rawKey},
+		},
+		{
+			name:     "no public keys",
+			manifest: manifestFor(checksum),
+			sig:      func(m []byte) []byte { return ed25519.Sign(priv, m) },
+		},
+	}
+
+	for _, mode := range []string{"", signatureOff, signatureOptional, signatureRequired} {
+		for _, tc := range cases {
+			t.Run(fmt.Sprintf("%s/%s", mode, tc.name), func(t *testing.T) {
+				var requests atomic.Int32
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests.Add(1)
+					if tc.manifest == nil {
+						http.NotFound(w, r)
+						return
+					}
+					switch r.URL.Path {
+					case "/" + version + "/SHA256SUMS":
+						w.Write(tc.manifest)
+					case "/" + version + "/SHA256SUMS.sig":
+						w.Write(tc.sig(tc.manifest))
+					default:
+						http.NotFound(w, r)
+					}
+				}))
+				defer srv.Close()
+
+				cfg := &SignatureConfig{
+					Mode:        mode,
+					ManifestURL: srv.URL + "/{version}/SHA256SUMS",
+					PublicKeys:  tc.keys,
+				}
+				err := verifySignature(t.Context(), testDownloader(t, 1), cfg, version, archive, checksum)
+
+				wantErr := mode == signatureRequired && !tc.ok
+				if (err != nil) != wantErr {
+					t.Errorf("verifySignature() = %v, want error %v", err, wantErr)
+				}
+				if off := mode == "" || mode == signatureOff; off && requests.Load() != 0 {
+					t.Errorf("mode %q fetched the manifest", mode)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifySignatureInvalidMode(t *testing.T) {
+	cfg := &SignatureConfig{Mode: "strict"}
+	if err := verifySignature(t.Context(), testDownloader(t, 1), cfg, "0.9.9", "uv.tar.gz", ""); err == nil {
+		t.Error("verifySignature() accepted an invalid mode")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockFileName marks a session directory as owned by a running uv-runner. It
+// holds the owner's process ID.
+const lockFileName = "uv-runner.lock"
+
+// unlockedGrace is how old a session directory without a lock file must be
+// before it is considered abandoned; younger ones may belong to a process that
+// has not written its lock yet.
+const unlockedGrace = time.Hour
+
+func writeLockFile(dir string) error {
+	return os.WriteFile(filepath.Join(dir, lockFileName), []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+}
+
+// staleReason explains why dir is no longer in use, or returns "" when it
+// may still belong to a live process.
+func staleReason(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, lockFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		info, err := os.Stat(dir)
+		if err == nil && time.Since(info.ModTime()) > unlockedGrace {
+			return "no lock file"
+		}
+		return ""
+	}
+	if err != nil {
+		return ""
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return "corrupt lock file"
+	}
+	if pid != os.Getpid() && !processAlive(pid) {
+		return fmt.Sprintf("process %d has exited", pid)
+	}
+	return ""
+}
+
+// sweepStaleDirs removes session directories left behind by uv-runner
+// processes that crashed or were killed, in every location makeRuntimeDir
+// may use. The directory of the current session, if any, is kept.
+func sweepStaleDirs(cfg *Config, current string, logf logFunc) (removed int) {
+	for _, candidate := range binDirCandidates(cfg) {
+		matches, _ := filepath.Glob(filepath.Join(candidate.dir, runtimeDirPattern))
+		for _, dir := range matches {
+			if dir == current {
+				continue
+			}
+			if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+			reason := staleReason(dir)
+			if reason == "" {
+				continue
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				logf("Could not remove stale directory %s: %v\n", dir, err)
+				continue
+			}
+			logf("Removed stale directory %s (%s)\n", dir, reason)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+)
+
+// toolPrefix marks script list entries that run a tool from a Python
+// package with `uv tool run` (as uvx does) instead of running a script, e.g.
+// "tool:ruff@0.6.9 check ." or "tool:--from httpie==3.2.4 http example.org".
+const toolPrefix = "tool:"
+
+// toolEntry is a parsed tool entry.
+type toolEntry struct {
+	// Args are passed to `uv tool run`: options such as --from, then the
+	// command, optionally pinned as command@version, and its arguments.
+	Args []string
+}
+
+// isToolEntry reports whether a script list entry runs a tool.
+func isToolEntry(entry string) bool {
+	return strings.HasPrefix(entry, toolPrefix)
+}
+
+// parseToolEntry returns the tool an entry runs, or nil if it is a script or
+// names no command. Arguments are separated by spaces; there is no quoting.
+func parseToolEntry(entry string) *toolEntry {
+	if !isToolEntry(entry) {
+		return nil
+	}
+	args := strings.Fields(strings.TrimPrefix(entry, toolPrefix))
+	if len(args) == 0 {
+		return nil
+	}
+	return &toolEntry{Args: args}
+}
+
+// name returns the command the tool runs, with its version if pinned.
+func (t *toolEntry) name() string {
+	for i := 0; i < len(t.Args); i++ {
+		arg := t.Args[i]
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+		// Skip the value of options that take one
+		if !strings.Contains(arg, "=") && toolOptionTakesValue(arg) {
+			i++
+		}
+	}
+	return strings.Join(t.Args, " ")
+}
+
+// toolOptionTakesValue reports whether a `uv tool run` option is followed by
+// a value.
+func toolOptionTakesValue(option string) bool {
+	switch option {
+	case "--from", "--with", "--with-editable", "--with-requirements", "-p", "--python", "--index", "--default-index":
+		return true
+	}
+	return false
+}
+
+// runArgs returns the uv arguments that run the tool with further arguments
+// appended to its own.
+func (t *toolEntry) runArgs(python string, extra []string) []string {
+	args := []string{"tool", "run"}
+	if python != "" {
+		args = append(args, "--python", python)
+	}
+	args = append(args, t.Args...)
+	return append(args, extra...)
+}
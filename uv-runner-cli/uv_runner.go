@@ -1,291 +1,374 @@
 package main
 
 import (
-	"archive/tar"
-	"archive/zip"
-	"compress/gzip"
-	"crypto/sha256"
+	"context"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
-	"strings"
 )
 
 const uvVersion = "0.9.5" // Update as needed
 
-func main() {
-	// Determine platform and architecture
-	platform := getPlatform()
-	arch := getArch()
-	target := fmt.Sprintf("%s-%s", arch, platform)
-
-	fmt.Printf("Detected platform: %s\n", target)
+// defaultScripts run when no scripts are given or embedded.
+var defaultScripts = []string{
+	"https://raw.githubusercontent.com/tnldart/openapi-servers/refs/heads/main/servers/memory/oneshot.py",
+	"https://raw.githubusercontent.com/tnldart/openapi-servers/refs/heads/main/servers/memory/main.py",
+}
 
-	// Create temp directory
-	tempDir, err := os.MkdirTemp("", "uv-runner-*")
+func main() {
+	cfg, err := loadConfig()
 	if err != nil {
 		panic(err)
 	}
-	defer os.RemoveAll(tempDir)
 
-	// Download and extract uv
-	uvPath, err := downloadUV(tempDir, target)
+	// Scripts, uv and launcher settings may be embedded in this executable
+	embedded, err := openPayload()
 	if err != nil {
-		panic(err)
+		fmt.Printf("Error reading embedded payload: %v\n", err)
+		os.Exit(1)
 	}
-
-	// Determine which scripts to run:
-	// - If the user supplies one or more paths/URLs as args, pass them through.
-	// - Otherwise, use the built-in defaults.
-	var scripts []string
-	if len(os.Args) > 1 {
-		// Use provided args as script paths/URLs
-		scripts = os.Args[1:]
-	} else {
-		// Fall back to defaults
-		scripts = []string{
-			"https://raw.githubusercontent.com/tnldart/openapi-servers/refs/heads/main/servers/memory/oneshot.py",
-			"https://raw.githubusercontent.com/tnldart/openapi-servers/refs/heads/main/servers/memory/main.py",
+	if embedded != nil {
+		embedded.manifest.applyTo(cfg)
+		if embedded.manifest.Name != "" {
+			fmt.Println(embedded.manifest.Name)
 		}
 	}
 
-	// Build command: uv run <scripts...>
-	fmt.Println("Running Python scripts...")
-	args := append([]string{"run"}, scripts...)
-	cmd := exec.Command(uvPath, args...)
-
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	flag.Usage = func() {
+		name := filepath.Base(os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [script-or-url...]\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] embed -o <output> [script-or-url...]\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] pack [-o <output>] <project.json>\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] bundle export|import|run ...\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] cache clean|size|prune|wipe\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] ps [stop]\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] prepare [-approve-scripts] [script-or-url...]\n", name)
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&cfg.Proxy, "proxy", cfg.Proxy, "HTTP(S) proxy URL for downloads and uv (password via UV_RUNNER_PROXY_PASSWORD)")
+	flag.StringVar(&cfg.ProxyUser, "proxy-user", cfg.ProxyUser, "user name for the proxy")
+	flag.StringVar(&cfg.Index, "index", cfg.Index, "package index URL to use instead of PyPI")
+	flag.StringVar(&cfg.CAFile, "ca-file", cfg.CAFile, "PEM file with additional CA certificates to trust")
+	flag.StringVar(&cfg.Signature.Mode, "signature", cfg.Signature.Mode, "signed manifest verification for uv: off, optional or required")
+	flag.StringVar(&cfg.UVConstraint, "uv-constraint", cfg.UVConstraint, "version constraint for using an installed uv (default \">=<uv version>\")")
+	flag.StringVar(&cfg.UVVersion, "uv-version", cfg.UVVersion, "uv release to download (default \""+uvVersion+"\")")
+	flag.BoolVar(&cfg.ManagedUV, "managed-uv", cfg.ManagedUV, "always download uv instead of using an installed one")
+	flag.BoolVar(&cfg.AllowSystemUV, "allow-system-uv", cfg.AllowSystemUV, "use an installed uv even when signature verification is required")
+	flag.StringVar(&cfg.Python, "python", cfg.Python, "Python version or interpreter for scripts without a python setting of their own")
+	flag.StringVar(&cfg.Profile, "profile", cfg.Profile, "keep uv's cache and Python installations in this uv-runner profile")
+	flag.StringVar(&cfg.BinDir, "bin-dir", cfg.BinDir, "directory to extract uv into (default: temp directory unless mounted noexec)")
+	approveScripts := flag.Bool("approve-scripts", false, "run remote scripts that are new or changed since they were last approved")
+	flag.Parse()
+
+	// Subcommands
+	commands := map[string]func(*Config, []string) error{
+		"bundle":  runBundle,
+		"cache":   runCache,
+		"embed":   runEmbed,
+		"pack":    runPack,
+		"prepare": runPrepare,
+		"ps":      runPS,
+	}
+	if command, ok := commands[flag.Arg(0)]; ok {
+		if err := command(cfg, flag.Args()[1:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	err = cmd.Run()
+	// Create temp directory in a location that allows executing uv
+	tempDir, err := makeRuntimeDir(cfg, logStdout)
 	if err != nil {
-		fmt.Printf("Error running uv: %v\n", err)
+		fmt.Printf("Error creating temp directory: %v\n", err)
 		os.Exit(1)
 	}
-}
+	defer os.RemoveAll(tempDir)
 
-func getPlatform() string {
-	switch runtime.GOOS {
-	case "darwin":
-		return "apple-darwin"
-	case "linux":
-		return "unknown-linux-gnu"
-	case "windows":
-		return "pc-windows-msvc"
-	default:
-		panic(fmt.Sprintf("Unsupported platform: %s", runtime.GOOS))
-	}
-}
+	// Remove what earlier sessions that crashed or were killed left behind
+	sweepStaleDirs(cfg, tempDir, logStdout)
+	warnSurvivors()
 
-func getArch() string {
-	switch runtime.GOARCH {
-	case "amd64":
-		return "x86_64"
-	case "arm64":
-		return "aarch64"
-	default:
-		panic(fmt.Sprintf("Unsupported architecture: %s", runtime.GOARCH))
+	uvPath, err := setUpUV(cfg, embedded, tempDir)
+	if err != nil {
+		panic(err)
 	}
-}
 
-func downloadUV(tempDir, target string) (string, error) {
-	// Determine file extension based on platform
-	var fileExt string
-	var tmpFilePattern string
-	if runtime.GOOS == "windows" {
-		fileExt = ".zip"
-		tmpFilePattern = "uv-*.zip"
-	} else {
-		fileExt = ".tar.gz"
-		tmpFilePattern = "uv-*.tar.gz"
+	scripts, scriptArgs, err := chooseScripts(embedded, flag.Args(), tempDir)
+	if err != nil {
+		fmt.Printf("Error extracting embedded scripts: %v\n", err)
+		os.Exit(1)
 	}
 
-	url := fmt.Sprintf("https://github.com/astral-sh/uv/releases/download/%s/uv-%s%s", uvVersion, target, fileExt)
-	checksumURL := url + ".sha256"
+	settings := cfg.script(scripts[0])
+	python := cfg.python(scripts[0])
 
-	fmt.Printf("Downloading uv from: %s\n", url)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", err
+	// A tool needs none of the script handling below; whatever follows it
+	// is passed on as its arguments
+	if tool := parseToolEntry(scripts[0]); tool != nil {
+		fmt.Printf("Running tool %s...\n", tool.name())
+		env, err := runEnv(cfg, embedded, tempDir)
+		if err != nil {
+			fmt.Printf("Error configuring uv environment: %v\n", err)
+			os.Exit(1)
+		}
+		args := tool.runArgs(python, append(scripts[1:], scriptArgs...))
+		if err := runUV(uvPath, args, env, settings.Detach); err != nil {
+			fmt.Printf("Error running uv: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to download uv: status %d", resp.StatusCode)
+	// Run verified local copies of remote scripts
+	client, err := cfg.httpClient()
+	if err != nil {
+		fmt.Printf("Error configuring downloads: %v\n", err)
+		os.Exit(1)
 	}
-
-	// Create a temporary file to store the downloaded archive
-	tmpFile, err := os.CreateTemp(tempDir, tmpFilePattern)
+	approve := approveFlag(*approveScripts)
+	dl := newDownloader(client, logStdout)
+	first, err := localScripts(context.Background(), dl, cfg, scripts[:1], tempDir, approve)
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
-
-	// Download to temp file and calculate checksum
-	hasher := sha256.New()
-	multiWriter := io.MultiWriter(tmpFile, hasher)
 
-	_, err = io.Copy(multiWriter, resp.Body)
+	// Parameters the script declares are given as flags after it
+	rest := scripts[1:]
+	var paramArgs, paramEnv []string
+	if source, err := os.ReadFile(first[0]); err == nil {
+		metadata, err := parseScriptMetadata(source)
+		if err != nil {
+			fmt.Printf("Error reading metadata of %s: %v\n", scripts[0], err)
+			os.Exit(1)
+		}
+		if metadata != nil {
+			if err := checkRequiresPython(metadata.RequiresPython, python); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if metadata != nil && len(metadata.Runner.Parameters) > 0 {
+			params := metadata.Runner.Parameters
+			values, remaining, err := parseParameterFlags(scriptLabel(scripts[0]), params, rest)
+			if err != nil {
+				os.Exit(2)
+			}
+			rest = remaining
+			paramArgs, paramEnv, err = parameterArgs(params, values)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+	rest, err = localScripts(context.Background(), dl, cfg, rest, filepath.Join(tempDir, "args"), approve)
 	if err != nil {
-		return "", fmt.Errorf("failed to save download: %w", err)
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
+	scripts = append(first, rest...)
 
-	// Calculate the actual checksum
-	actualChecksum := fmt.Sprintf("%x", hasher.Sum(nil))
+	// Build command: uv run <scripts...>
+	fmt.Println("Running Python scripts...")
+	args := []string{"run"}
+	if python != "" {
+		args = append(args, "--python", python)
+	}
+	args = append(args, scripts...)
+	args = append(args, scriptArgs...)
+	args = append(args, paramArgs...)
 
-	// Download and verify checksum
-	fmt.Printf("Downloading checksum from: %s\n", checksumURL)
-	checksumResp, err := http.Get(checksumURL)
+	env, err := runEnv(cfg, embedded, tempDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to download checksum: %w", err)
+		fmt.Printf("Error configuring uv environment: %v\n", err)
+		os.Exit(1)
 	}
-	defer checksumResp.Body.Close()
+	env = append(env, paramEnv...)
 
-	if checksumResp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to download checksum: status %d", checksumResp.StatusCode)
+	if err := runUV(uvPath, args, env, settings.Detach); err != nil {
+		fmt.Printf("Error running uv: %v\n", err)
+		os.Exit(1)
 	}
+}
 
-	checksumBytes, err := io.ReadAll(checksumResp.Body)
+// runEnv returns the environment uv runs scripts in: ours, with proxy, CA
+// and index settings passed on to uv, and the launcher's variables.
+func runEnv(cfg *Config, embedded *payload, tempDir string) ([]string, error) {
+	env, err := cfg.uvEnv(tempDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to read checksum: %w", err)
+		return nil, err
 	}
-
-	// Parse expected checksum (typically in format: "checksum filename")
-	expectedChecksum := strings.Fields(string(checksumBytes))[0]
-
-	// Verify checksum
-	if actualChecksum != expectedChecksum {
-		return "", fmt.Errorf("checksum verification failed: expected %s, got %s", expectedChecksum, actualChecksum)
+	env = append(os.Environ(), env...)
+	if embedded != nil {
+		env = append(env, embedded.manifest.environ()...)
 	}
+	return env, nil
+}
 
-	fmt.Println("Checksum verification successful")
+// runUV runs uv with its output going to ours, and records its process while
+// it runs so that it can be found should we crash. Unless detach is set, uv
+// is terminated when we die.
+func runUV(uvPath string, args, env []string, detach bool) error {
+	cmd := exec.Command(uvPath, args...)
+	if !detach {
+		runtime.LockOSThread()
+		dieWithParent(cmd)
+	}
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
 
-	// Reset file position for reading
-	_, err = tmpFile.Seek(0, 0)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	record, err := recordProcess(cmd, false, detach)
 	if err != nil {
-		return "", fmt.Errorf("failed to reset file position: %w", err)
+		fmt.Printf("Warning: could not record process %d: %v\n", cmd.Process.Pid, err)
 	}
+	err = cmd.Wait()
+	record.forget()
+	return err
+}
 
-	// Extract archive based on platform
-	if runtime.GOOS == "windows" {
-		return extractZip(tmpFile, tempDir)
-	} else {
-		return extractTarGz(tmpFile, tempDir)
+// chooseScripts determines which scripts to run:
+//   - If the user supplies one or more paths/URLs as args, pass them through.
+//   - Otherwise, use the scripts embedded in this executable, if any, along
+//     with their arguments.
+//   - Otherwise, use the built-in defaults.
+func chooseScripts(embedded *payload, args []string, tempDir string) (scripts, scriptArgs []string, err error) {
+	if len(args) > 0 {
+		return args, nil, nil
+	}
+	if embedded != nil && len(embedded.manifest.Scripts) > 0 {
+		scripts, err = embedded.scripts(tempDir)
+		return scripts, embedded.manifest.Args, err
 	}
+	return defaultScripts, nil, nil
 }
 
-func extractTarGz(file *os.File, tempDir string) (string, error) {
-	// Extract tar.gz from the verified file
-	gzr, err := gzip.NewReader(file)
+// setUpUV returns an installed uv if a suitable one exists, otherwise it
+// downloads and extracts our own into tempDir.
+func setUpUV(cfg *Config, embedded *payload, tempDir string) (string, error) {
+	// Determine platform and architecture
+	target, err := detectTarget()
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to detect platform: %w", err)
 	}
-	defer gzr.Close()
 
-	tr := tar.NewReader(gzr)
+	fmt.Printf("Detected platform: %s\n", target)
 
-	var uvPath string
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
+	if cfg.useSystemUV(logStdout) {
+		uvPath, err := findSystemUV(cfg.uvConstraint(), logStdout)
 		if err != nil {
-			return "", err
+			return "", fmt.Errorf("failed to check installed uv: %w", err)
 		}
+		if uvPath != "" {
+			fmt.Printf("Using installed uv: %s\n", uvPath)
+			return uvPath, nil
+		}
+	}
+	return downloadUV(context.Background(), cfg, embedded, tempDir, target)
+}
 
-		fmt.Printf("Found file in archive: %s\n", header.Name)
+// approveFlag approves remote scripts as the -approve-scripts flag says,
+// printing them for review.
+func approveFlag(approved bool) approveFunc {
+	return func(review *scriptReview) bool {
+		fmt.Printf("%s (SHA-256 %s):\n\n%s\n", review.Title(), review.SHA256, review.Text)
+		if !approved {
+			fmt.Println("Review the script above and rerun with -approve-scripts to run it.")
+		}
+		return approved
+	}
+}
 
-		// Look for uv executable (could be nested in a directory)
-		if filepath.Base(header.Name) == "uv" || filepath.Base(header.Name) == "uv.exe" {
-			uvPath = filepath.Join(tempDir, filepath.Base(header.Name))
+// parameterFlag sets the value of a script parameter from the command line.
+type parameterFlag struct {
+	param  *ScriptParameter
+	values map[string]string
+}
 
-			file, err := os.Create(uvPath)
-			if err != nil {
-				return "", err
-			}
+func (f *parameterFlag) String() string {
+	if f == nil || f.param == nil {
+		return ""
+	}
+	return f.param.defaultValue()
+}
 
-			_, err = io.Copy(file, tr)
-			file.Close()
-			if err != nil {
-				return "", err
-			}
+func (f *parameterFlag) Set(value string) error {
+	if err := f.param.check(value); err != nil {
+		return err
+	}
+	f.values[f.param.Name] = value
+	return nil
+}
 
-			// Make executable
-			err = os.Chmod(uvPath, 0755)
-			if err != nil {
-				return "", err
-			}
+func (f *parameterFlag) IsBoolFlag() bool {
+	return f.param.Type == paramBool
+}
 
-			break
-		}
+// parseParameterFlags reads values for a script's parameters from the
+// arguments following it, returning the arguments that are left.
+func parseParameterFlags(script string, params []ScriptParameter, args []string) (map[string]string, []string, error) {
+	fs := flag.NewFlagSet(script, flag.ContinueOnError)
+	values := map[string]string{}
+	for i := range params {
+		fs.Var(&parameterFlag{&params[i], values}, params[i].Name, params[i].Help)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
 	}
+	return values, fs.Args(), nil
+}
 
-	if uvPath == "" {
-		return "", fmt.Errorf("uv binary not found in archive")
+// scriptLabel shows just the filename or last part of URL, or the tool.
+func scriptLabel(script string) string {
+	if tool := parseToolEntry(script); tool != nil {
+		return tool.name()
 	}
+	script, _ = splitPin(script)
+	return path.Base(script)
+}
 
-	return uvPath, nil
+// logStdout is the CLI's logFunc.
+func logStdout(format string, args ...any) {
+	fmt.Printf(format, args...)
 }
 
-func extractZip(file *os.File, tempDir string) (string, error) {
-	// Get file info for zip reader
-	fileInfo, err := file.Stat()
+func downloadUV(ctx context.Context, cfg *Config, embedded *payload, tempDir, target string) (string, error) {
+	// Create a temporary file to store the archive
+	tmpFile, err := os.CreateTemp(tempDir, "uv-*-"+uvArchiveName(target))
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to create temp file: %w", err)
 	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
 
-	// Create zip reader
-	zr, err := zip.NewReader(file, fileInfo.Size())
+	client, err := cfg.httpClient()
 	if err != nil {
 		return "", err
 	}
+	dl := newDownloader(client, logStdout)
 
-	var uvPath string
-	for _, f := range zr.File {
-		fmt.Printf("Found file in archive: %s\n", f.Name)
-
-		// Look for uv executable (could be nested in a directory)
-		if filepath.Base(f.Name) == "uv.exe" || filepath.Base(f.Name) == "uv" {
-			uvPath = filepath.Join(tempDir, filepath.Base(f.Name))
-
-			rc, err := f.Open()
-			if err != nil {
-				return "", err
-			}
-
-			outFile, err := os.Create(uvPath)
-			if err != nil {
-				rc.Close()
-				return "", err
-			}
-
-			_, err = io.Copy(outFile, rc)
-			outFile.Close()
-			rc.Close()
-			if err != nil {
-				return "", err
-			}
-
-			// Make executable
-			err = os.Chmod(uvPath, 0755)
-			if err != nil {
-				return "", err
-			}
-
-			break
-		}
+	// An archive embedded in this executable is verified just like a
+	// downloaded one
+	if uv := embedded.embeddedUV(cfg.uvVersion(), target); uv != nil {
+		fmt.Printf("Using embedded uv archive: %s\n", uv.File)
+		err = embedded.copyEmbeddedUV(ctx, dl, cfg, uv, tmpFile)
+	} else {
+		err = fetchUVArchive(ctx, dl, cfg, tmpFile, target)
 	}
-
-	if uvPath == "" {
-		return "", fmt.Errorf("uv binary not found in archive")
+	if err != nil {
+		return "", err
 	}
 
-	return uvPath, nil
+	fmt.Println("Checksum verification successful")
+
+	return extractUVArchive(tmpFile, tempDir, target)
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runtimeDirPattern names the per-session directories uv-runner creates for
+// the uv binary, CA bundles and extracted scripts.
+const runtimeDirPattern = "uv-runner-*"
+
+// binDirCandidate is a parent directory to create the session directory in.
+type binDirCandidate struct {
+	dir    string
+	source string
+}
+
+func binDirCandidates(cfg *Config) []binDirCandidate {
+	if cfg.BinDir != "" {
+		return []binDirCandidate{{cfg.BinDir, "configured bin_dir"}}
+	}
+
+	candidates := []binDirCandidate{{os.TempDir(), "temp directory"}}
+	if dir, err := os.UserCacheDir(); err == nil {
+		candidates = append(candidates, binDirCandidate{filepath.Join(dir, "uv-runner"), "cache directory"})
+	}
+	if dir, err := dataDir(); err == nil {
+		candidates = append(candidates, binDirCandidate{filepath.Join(dir, "bin"), "data directory"})
+	}
+	return candidates
+}
+
+// makeRuntimeDir creates the session directory in the first candidate
+// location where files may be executed. Hardened hosts often mount /tmp
+// noexec, which would otherwise surface as a confusing permission error when
+// uv is started.
+func makeRuntimeDir(cfg *Config, logf logFunc) (string, error) {
+	var problems []string
+	for _, candidate := range binDirCandidates(cfg) {
+		dir, err := tryRuntimeDir(candidate.dir)
+		if err == nil {
+			if len(problems) > 0 {
+				logf("Using %s %s for executables\n", candidate.source, candidate.dir)
+			}
+			return dir, nil
+		}
+		logf("Cannot use %s %s: %v\n", candidate.source, candidate.dir, err)
+		problems = append(problems, fmt.Sprintf("%s %s: %v", candidate.source, candidate.dir, err))
+	}
+	return "", fmt.Errorf("no directory allows executing files (%s); set bin_dir or UV_RUNNER_BIN_DIR to a directory on a filesystem without noexec",
+		strings.Join(problems, "; "))
+}
+
+func tryRuntimeDir(parent string) (string, error) {
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp(parent, runtimeDirPattern)
+	if err != nil {
+		return "", err
+	}
+	if err := writeLockFile(dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	if err := checkExecutable(dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
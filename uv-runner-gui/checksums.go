@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// pinnedChecksum returns the pinned digest for a uv version and target, and
+// where it came from. Pinned digests take precedence over the .sha256 file
+// published next to each archive, so a mirror serving a tampered archive with
+// a matching sidecar is still caught. Entries from the config may add to the
+// built-in table in checksums_table.go but not contradict it.
+func (c *Config) pinnedChecksum(version, target string) (sum, source string, err error) {
+	builtin := strings.ToLower(pinnedChecksums[version][target])
+	configured := strings.ToLower(c.Checksums[version][target])
+
+	switch {
+	case builtin != "" && configured != "" && builtin != configured:
+		return "", "", fmt.Errorf("configured checksum %s for uv %s (%s) conflicts with built-in checksum %s", configured, version, target, builtin)
+	case builtin != "":
+		return builtin, "built-in", nil
+	case configured != "":
+		return configured, "config", nil
+	default:
+		return "", "", nil
+	}
+}
+
+// fileSHA256 returns the hex digest of the whole file.
+func fileSHA256(f *os.File) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to reset file position: %w", err)
+	}
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", fmt.Errorf("failed to hash download: %w", err)
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// verifyArchive checks the uv release archive in f, whether downloaded or
+// taken from a payload or bundle. When a pinned digest exists the published
+// .sha256 sidecar must agree with it and the archive must match it; otherwise
+// the sidecar alone is trusted. The signed manifest is checked afterwards if
+// configured.
+func verifyArchive(ctx context.Context, dl *downloader, cfg *Config, f *os.File, version, target string) error {
+	url := uvArchiveURL(version, target)
+	checksumURL := url + ".sha256"
+
+	// Calculate the actual checksum over the complete file, which may have
+	// been assembled from several resumed attempts
+	actualChecksum, err := fileSHA256(f)
+	if err != nil {
+		return err
+	}
+
+	pinned, source, err := cfg.pinnedChecksum(version, target)
+	if err != nil {
+		return err
+	}
+
+	// Parse expected checksum (typically in format: "checksum filename")
+	var sidecar string
+	checksumBytes, err := dl.fetchBytes(ctx, checksumURL)
+	if err == nil {
+		fields := strings.Fields(string(checksumBytes))
+		if len(fields) == 0 {
+			err = fmt.Errorf("checksum file is empty")
+		} else {
+			sidecar = strings.ToLower(fields[0])
+		}
+	}
+	if err != nil {
+		if pinned == "" {
+			return fmt.Errorf("failed to download checksum: %w", err)
+		}
+		dl.logf("Could not fetch %s (%v); relying on %s pinned checksum\n", checksumURL, err, source)
+	}
+
+	if pinned != "" && sidecar != "" && sidecar != pinned {
+		return fmt.Errorf("published checksum %s for uv %s (%s) disagrees with %s pinned checksum %s; the download source may be compromised",
+			sidecar, version, target, source, pinned)
+	}
+
+	expectedChecksum := pinned
+	if expectedChecksum == "" {
+		dl.logf("No pinned checksum for uv %s (%s); relying on the published checksum\n", version, target)
+		expectedChecksum = sidecar
+	}
+
+	// Verify checksum
+	if actualChecksum != expectedChecksum {
+		return fmt.Errorf("checksum verification failed: expected %s, got %s", expectedChecksum, actualChecksum)
+	}
+
+	return verifySignature(ctx, dl, &cfg.Signature, version, path.Base(url), actualChecksum)
+}
@@ -0,0 +1,61 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+const prSetChildSubreaper = 36
+
+// becomeSubreaper makes processes orphaned by our children, such as the
+// Python interpreters started by uv, our children rather than init's, so
+// reapGroup can clean them up.
+func becomeSubreaper() error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// dieWithParent has the kernel send SIGTERM to cmd's process when uv-runner
+// dies, however it dies; uv passes the signal on to the Python process. The
+// signal is tied to the thread that starts the process, so the calling
+// goroutine must stay locked to its thread until the process has exited.
+func dieWithParent(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Pdeathsig = syscall.SIGTERM
+}
+
+// reapGroup terminates what is left of a process group after its leader has
+// exited and reaps the members that were reparented to us.
+func reapGroup(pgid int) {
+	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+		return
+	}
+	deadline := time.Now().Add(stopGracePeriod)
+	killed := false
+	for {
+		pid, err := syscall.Wait4(-pgid, nil, syscall.WNOHANG, nil)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			// None of the group's members are (still) our children
+			return
+		}
+		if pid > 0 {
+			continue
+		}
+		if !killed && time.Now().After(deadline) {
+			syscall.Kill(-pgid, syscall.SIGKILL)
+			killed = true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package main
+
+import (
+	"os/exec"
+)
+
+// becomeSubreaper is only supported on Linux.
+func becomeSubreaper() error {
+	return nil
+}
+
+// dieWithParent is only supported on Linux; elsewhere processes outlive a
+// uv-runner that is killed.
+func dieWithParent(cmd *exec.Cmd) {}
+
+// reapGroup is only needed on Linux, where we may be a subreaper.
+func reapGroup(pgid int) {}
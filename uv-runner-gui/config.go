@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+// Config holds user settings shared by the CLI and GUI. It is read from
+// config.json in the uv-runner config directory, or from the file named by
+// UV_RUNNER_CONFIG, and individual settings can be overridden with
+// environment variables.
+type Config struct {
+	// Proxy is the URL of an HTTP(S) proxy used for uv downloads and passed
+	// on to uv for script and package fetches. When empty the standard
+	// HTTP_PROXY/HTTPS_PROXY/NO_PROXY variables apply.
+	Proxy         string `json:"proxy,omitempty"`
+	ProxyUser     string `json:"proxy_user,omitempty"`
+	ProxyPassword string `json:"proxy_password,omitempty"`
+
+	// CAFile names a PEM file with additional CA certificates to trust, for
+	// networks that intercept TLS.
+	CAFile string `json:"ca_file,omitempty"`
+
+	// Index replaces PyPI as the package index uv installs from, and
+	// ExtraIndexes are searched before it. IndexCredentials holds user names
+	// and passwords for indexes, keyed by host (with the port, if the URL
+	// has one); they are passed to uv in its environment.
+	Index            string                      `json:"index,omitempty"`
+	ExtraIndexes     []string                    `json:"extra_indexes,omitempty"`
+	IndexCredentials map[string]IndexCredentials `json:"index_credentials,omitempty"`
+
+	// Checksums extends the built-in table of pinned uv archive digests,
+	// keyed by uv version and then by target triple.
+	Checksums map[string]map[string]string `json:"checksums,omitempty"`
+
+	// UVVersion is the uv release to download, defaulting to the version
+	// this build of uv-runner was tested with.
+	UVVersion string `json:"uv_version,omitempty"`
+
+	// UVConstraint selects which already installed uv binaries may be used
+	// instead of downloading one, e.g. ">=0.8, <1.0". It defaults to the
+	// version uv-runner would download or newer. ManagedUV skips the search
+	// and always downloads. An installed uv has not been verified, so with
+	// signatures required it is only used if AllowSystemUV is set.
+	UVConstraint  string `json:"uv_constraint,omitempty"`
+	ManagedUV     bool   `json:"managed_uv,omitempty"`
+	AllowSystemUV bool   `json:"allow_system_uv,omitempty"`
+
+	// BinDir is where uv-runner extracts uv and other files it executes.
+	// When empty, the system temp directory is used if it allows executing
+	// files, falling back to the user cache and data directories.
+	BinDir string `json:"bin_dir,omitempty"`
+
+	// Python is passed to uv as --python to choose the interpreter scripts
+	// run with: a version such as "3.12", a request such as "pypy@3.10" or
+	// the path of an interpreter. When empty uv picks one.
+	Python string `json:"python,omitempty"`
+
+	// Profile, when set, gives scripts a uv cache and Python installations
+	// of their own in the uv-runner data directory instead of sharing the
+	// user's.
+	Profile string `json:"profile,omitempty"`
+
+	// Signature configures verification of a signed checksum manifest for
+	// uv archives.
+	Signature SignatureConfig `json:"signature"`
+
+	// Scripts holds settings for individual scripts, keyed by their path or
+	// URL as passed to uv-runner, without a "#sha256=" pin.
+	Scripts map[string]ScriptSettings `json:"scripts,omitempty"`
+}
+
+// ScriptSettings are the settings for one script.
+type ScriptSettings struct {
+	// Detach lets the script keep running after uv-runner exits. By default
+	// it is terminated along with uv-runner, even when uv-runner is killed
+	// (on Linux).
+	Detach bool `json:"detach,omitempty"`
+
+	// SHA256 pins the content of a remote script. uv-runner fetches remote
+	// scripts itself and refuses to run one that does not match.
+	SHA256 string `json:"sha256,omitempty"`
+
+	// Python overrides Config.Python for the script.
+	Python string `json:"python,omitempty"`
+}
+
+// configDir returns the directory holding uv-runner's settings.
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "uv-runner"), nil
+}
+
+func configPath() (string, error) {
+	if path := os.Getenv("UV_RUNNER_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// loadConfig reads the settings file, if any, and applies environment
+// overrides. A missing file is not an error.
+func loadConfig() (*Config, error) {
+	cfg := &Config{}
+
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+
+	overrides := map[string]*string{
+		"UV_RUNNER_PROXY":          &cfg.Proxy,
+		"UV_RUNNER_PROXY_USER":     &cfg.ProxyUser,
+		"UV_RUNNER_PROXY_PASSWORD": &cfg.ProxyPassword,
+		"UV_RUNNER_CA_FILE":        &cfg.CAFile,
+		"UV_RUNNER_INDEX":          &cfg.Index,
+		"UV_RUNNER_SIGNATURE_MODE": &cfg.Signature.Mode,
+		"UV_RUNNER_UV_CONSTRAINT":  &cfg.UVConstraint,
+		"UV_RUNNER_UV_VERSION":     &cfg.UVVersion,
+		"UV_RUNNER_BIN_DIR":        &cfg.BinDir,
+		"UV_RUNNER_PYTHON":         &cfg.Python,
+		"UV_RUNNER_PROFILE":        &cfg.Profile,
+	}
+	for name, field := range overrides {
+		if value, ok := os.LookupEnv(name); ok {
+			*field = value
+		}
+	}
+	for name, field := range map[string]*bool{
+		"UV_RUNNER_MANAGED_UV":      &cfg.ManagedUV,
+		"UV_RUNNER_ALLOW_SYSTEM_UV": &cfg.AllowSystemUV,
+	} {
+		if value, ok := os.LookupEnv(name); ok {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*field = enabled
+		}
+	}
+
+	return cfg, nil
+}
+
+// uvVersion returns the uv release to install.
+func (c *Config) uvVersion() string {
+	if c.UVVersion != "" {
+		return c.UVVersion
+	}
+	return uvVersion
+}
+
+// uvConstraint returns the constraint an installed uv must satisfy to be
+// used, by default the version uv-runner would download or newer.
+func (c *Config) uvConstraint() string {
+	if c.UVConstraint != "" {
+		return c.UVConstraint
+	}
+	return ">=" + c.uvVersion()
+}
+
+// useSystemUV reports whether to look for an installed uv before downloading
+// one.
+func (c *Config) useSystemUV(logf logFunc) bool {
+	if c.ManagedUV {
+		return false
+	}
+	if c.Signature.Mode == signatureRequired && !c.AllowSystemUV {
+		logf("Signature verification is required; downloading uv instead of using an installed one (set allow_system_uv to allow it)\n")
+		return false
+	}
+	return true
+}
+
+// script returns the settings for a script. uv runs the first of the scripts
+// it is given, passing the others as arguments, so that is the one to look up
+// for a command.
+func (c *Config) script(ref string) ScriptSettings {
+	ref, _ = splitPin(ref)
+	return c.Scripts[ref]
+}
+
+// python returns the Python to run a script with, or "" to let uv choose.
+func (c *Config) python(ref string) string {
+	if python := c.script(ref).Python; python != "" {
+		return python
+	}
+	return c.Python
+}
+
+// dataDir returns the directory for uv-runner's persistent data:
+// $XDG_DATA_HOME/uv-runner (~/.local/share/uv-runner) on Linux and other
+// Unix systems, ~/Library/Application Support/uv-runner on macOS and
+// %LocalAppData%\uv-runner on Windows.
+func dataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return filepath.Join(dir, "uv-runner"), nil
+		}
+		return "", errors.New("%LocalAppData% is not set")
+	case "darwin", "ios":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "uv-runner"), nil
+	default:
+		if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+			return filepath.Join(dir, "uv-runner"), nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".local", "share", "uv-runner"), nil
+	}
+}
@@ -0,0 +1,829 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// sessionState is the lifecycle of a GUI session: uv is set up while
+// initializing, after which scripts can be run or prepared whenever the
+// session is ready.
+type sessionState int
+
+const (
+	stateInitializing sessionState = iota
+	stateReady
+	stateRunning
+	statePreparing
+	stateCleaning
+	stateFailed
+)
+
+func (s sessionState) String() string {
+	switch s {
+	case stateInitializing:
+		return "initializing"
+	case stateReady:
+		return "ready"
+	case stateRunning:
+		return "running"
+	case statePreparing:
+		return "preparing"
+	case stateCleaning:
+		return "cleaning the cache"
+	case stateFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("sessionState(%d)", int(s))
+	}
+}
+
+// transitions lists the states each state may move to.
+var transitions = map[sessionState][]sessionState{
+	stateInitializing: {stateReady, stateFailed},
+	stateReady:        {stateRunning, statePreparing, stateCleaning},
+	stateRunning:      {stateReady},
+	statePreparing:    {stateReady},
+	stateCleaning:     {stateReady},
+	stateFailed:       {stateInitializing, stateReady},
+}
+
+// controller owns the state of a GUI session and the work of setting up uv
+// and running scripts, apart from the widgets that present it. Its methods
+// may be called from any goroutine.
+type controller struct {
+	config  *Config
+	payload *payload
+
+	// output receives log messages and script output. onChange is called
+	// after the state or the script list changes, from the goroutine that
+	// changed it.
+	output   func(text string)
+	onChange func(state sessionState)
+
+	// approve is asked before running a remote script that is new or has
+	// changed since it was approved. It is called from a background
+	// goroutine and may block until the user decides.
+	approve approveFunc
+
+	mu      sync.Mutex
+	state   sessionState
+	err     error // why initialization failed
+	scripts []string
+	uvPath  string
+	tempDir string
+	running []*exec.Cmd      // Track running processes
+	adopted []*processRecord // Processes taken over from an earlier session
+
+	// cancelSetUp stops the initialization in progress, if any. attempt
+	// counts initializations so the result of a superseded one is ignored.
+	cancelSetUp context.CancelFunc
+	attempt     int
+}
+
+func newController(cfg *Config, embedded *payload, scripts []string, output func(string), onChange func(sessionState), approve approveFunc) *controller {
+	return &controller{
+		config:   cfg,
+		payload:  embedded,
+		output:   output,
+		onChange: onChange,
+		approve:  approve,
+		scripts:  scripts,
+	}
+}
+
+// logf is the controller's logFunc.
+func (c *controller) logf(format string, args ...any) {
+	c.output(fmt.Sprintf(format, args...))
+}
+
+// current returns the session state and, when it is stateFailed, the reason.
+func (c *controller) current() (sessionState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state, c.err
+}
+
+// moveLocked changes the state if the transition is allowed. The caller must
+// hold mu and call onChange once it has released it.
+func (c *controller) moveLocked(to sessionState) error {
+	for _, allowed := range transitions[c.state] {
+		if allowed == to {
+			c.state = to
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot go from %s to %s", c.state, to)
+}
+
+func (c *controller) move(to sessionState, err error) error {
+	c.mu.Lock()
+	moveErr := c.moveLocked(to)
+	if moveErr == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+	if moveErr == nil {
+		c.onChange(to)
+	}
+	return moveErr
+}
+
+func (c *controller) changed() {
+	state, _ := c.current()
+	c.onChange(state)
+}
+
+// scriptCount and scriptAt give the list widget access to the scripts.
+func (c *controller) scriptCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.scripts)
+}
+
+func (c *controller) scriptAt(i int) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i < 0 || i >= len(c.scripts) {
+		return "", false
+	}
+	return c.scripts[i], true
+}
+
+func (c *controller) addScript(script string) {
+	c.mu.Lock()
+	c.scripts = append(c.scripts, script)
+	c.mu.Unlock()
+	c.changed()
+}
+
+func (c *controller) removeScript(i int) bool {
+	c.mu.Lock()
+	if i < 0 || i >= len(c.scripts) {
+		c.mu.Unlock()
+		return false
+	}
+	c.scripts = append(c.scripts[:i], c.scripts[i+1:]...)
+	c.mu.Unlock()
+	c.changed()
+	return true
+}
+
+func (c *controller) setScripts(scripts []string) {
+	c.mu.Lock()
+	c.scripts = scripts
+	c.mu.Unlock()
+	c.changed()
+}
+
+// uv returns the uv binary in use, or "" before initialization succeeds.
+func (c *controller) uv() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.uvPath
+}
+
+// errSetUpCancelled is the failure reason after cancelInitialize.
+var errSetUpCancelled = errors.New("cancelled")
+
+// initialize starts setting up uv in the background, moving the session to
+// stateReady or stateFailed when done. After a failure it may be called
+// again to retry.
+func (c *controller) initialize() error {
+	c.mu.Lock()
+	if c.state == stateInitializing && c.cancelSetUp != nil {
+		c.mu.Unlock()
+		return errors.New("UV is already being set up")
+	}
+	if c.state != stateInitializing {
+		if err := c.moveLocked(stateInitializing); err != nil {
+			c.mu.Unlock()
+			return err
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancelSetUp = cancel
+	c.attempt++
+	attempt := c.attempt
+	c.err = nil
+	c.mu.Unlock()
+	c.onChange(stateInitializing)
+
+	go func() {
+		uvPath, err := c.setUp(ctx)
+		if err != nil && ctx.Err() != nil {
+			err = errSetUpCancelled
+		}
+		cancel()
+		c.finishSetUp(attempt, uvPath, err)
+	}()
+	return nil
+}
+
+func (c *controller) finishSetUp(attempt int, uvPath string, err error) {
+	c.mu.Lock()
+	if attempt != c.attempt {
+		// Superseded by useLocalUV
+		c.mu.Unlock()
+		return
+	}
+	c.cancelSetUp = nil
+	c.err = err
+	next := stateReady
+	if err != nil {
+		next = stateFailed
+	} else {
+		c.uvPath = uvPath
+	}
+	moveErr := c.moveLocked(next)
+	c.mu.Unlock()
+	if moveErr != nil {
+		return
+	}
+
+	switch {
+	case err == errSetUpCancelled:
+		c.logf("UV setup cancelled\n")
+	case err != nil:
+		c.logf("Error initializing UV: %v\n", err)
+	default:
+		c.logf("UV initialized successfully!\n")
+	}
+	c.onChange(next)
+}
+
+// cancelInitialize stops setting up uv; the session then fails and can be
+// retried.
+func (c *controller) cancelInitialize() {
+	c.mu.Lock()
+	cancel := c.cancelSetUp
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// useLocalUV switches to a uv binary chosen by the user, abandoning a setup
+// in progress. Unlike an installed uv found automatically, its version is
+// not checked against the constraint.
+func (c *controller) useLocalUV(path string) error {
+	version, err := uvBinaryVersion(path)
+	if err != nil {
+		return fmt.Errorf("%s is not a working uv: %w", path, err)
+	}
+
+	c.mu.Lock()
+	if c.tempDir == "" {
+		c.mu.Unlock()
+		return errors.New("the session directory has not been created yet; retry the setup first")
+	}
+	if err := c.moveLocked(stateReady); err != nil {
+		c.mu.Unlock()
+		return fmt.Errorf("cannot change UV while %s", c.state)
+	}
+	if c.cancelSetUp != nil {
+		c.cancelSetUp()
+		c.cancelSetUp = nil
+	}
+	c.attempt++
+	c.uvPath = path
+	c.err = nil
+	c.mu.Unlock()
+
+	c.logf("Using UV %s: %s\n", version, path)
+	c.onChange(stateReady)
+	return nil
+}
+
+func (c *controller) setUp(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	tempDir := c.tempDir
+	c.mu.Unlock()
+
+	if tempDir == "" {
+		// Create temp directory in a location that allows executing uv
+		dir, err := makeRuntimeDir(c.config, c.logf)
+		if err != nil {
+			return "", fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		tempDir = dir
+		c.mu.Lock()
+		c.tempDir = dir
+		c.mu.Unlock()
+
+		// Remove what earlier sessions that crashed or were killed left
+		// behind
+		sweepStaleDirs(c.config, tempDir, c.logf)
+
+		// Scripts embedded in this executable replace the defaults
+		if c.payload != nil && len(c.payload.manifest.Scripts) > 0 {
+			scripts, err := c.payload.scripts(tempDir)
+			if err != nil {
+				return "", fmt.Errorf("failed to extract embedded scripts: %w", err)
+			}
+			c.setScripts(scripts)
+		}
+	}
+
+	// Use an installed uv if a suitable one exists, otherwise download and
+	// extract our own
+	if c.config.useSystemUV(c.logf) {
+		uvPath, err := findSystemUV(c.config.uvConstraint(), c.logf)
+		if err != nil {
+			return "", fmt.Errorf("failed to check installed UV: %w", err)
+		}
+		if uvPath != "" {
+			c.logf("Using installed UV: %s\n", uvPath)
+			return uvPath, nil
+		}
+	}
+	return c.downloadUV(ctx, tempDir)
+}
+
+func (c *controller) downloadUV(ctx context.Context, tempDir string) (string, error) {
+	target, err := detectTarget()
+	if err != nil {
+		return "", err
+	}
+
+	c.logf("Detected platform: %s\n", target)
+
+	// Create a temporary file to store the archive
+	tmpFile, err := os.CreateTemp(tempDir, "uv-*-"+uvArchiveName(target))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	// An archive embedded in this executable is verified just like a
+	// downloaded one
+	client, err := c.config.httpClient()
+	if err != nil {
+		return "", err
+	}
+	dl := newDownloader(client, c.logf)
+	if uv := c.payload.embeddedUV(c.config.uvVersion(), target); uv != nil {
+		c.logf("Using embedded UV archive: %s\n", uv.File)
+		err = c.payload.copyEmbeddedUV(ctx, dl, c.config, uv, tmpFile)
+	} else {
+		err = fetchUVArchive(ctx, dl, c.config, tmpFile, target)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	c.logf("Checksum verification successful\n")
+	c.logf("Extracting UV binary...\n")
+
+	return extractUVArchive(tmpFile, tempDir, target)
+}
+
+// metadata reads the inline metadata of a script. Tools have none.
+func (c *controller) metadata(ctx context.Context, ref string) (*ScriptMetadata, error) {
+	if isToolEntry(ref) {
+		return nil, nil
+	}
+	client, err := c.config.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	source, err := readScript(ctx, newDownloader(client, c.logf), ref)
+	if err != nil {
+		return nil, err
+	}
+	return parseScriptMetadata(source)
+}
+
+// pythonInstallation is an entry of `uv python list`: an installed Python,
+// or one uv can download when Path is nil.
+type pythonInstallation struct {
+	Version        string  `json:"version"`
+	Implementation string  `json:"implementation"`
+	Variant        string  `json:"variant"`
+	Path           *string `json:"path"`
+}
+
+// request returns how to ask uv for the installation with --python.
+func (p pythonInstallation) request() string {
+	request := p.Version
+	if p.Implementation != "" && p.Implementation != "cpython" {
+		request = p.Implementation + "@" + request
+	}
+	if p.Variant == "freethreaded" {
+		request += "t"
+	}
+	return request
+}
+
+// pythons lists the Python installations uv finds and the versions it can
+// download.
+func (c *controller) pythons(ctx context.Context) ([]pythonInstallation, error) {
+	c.mu.Lock()
+	uvPath, tempDir := c.uvPath, c.tempDir
+	c.mu.Unlock()
+	if uvPath == "" {
+		return nil, errors.New("uv is not set up")
+	}
+
+	uvEnv, err := c.config.uvEnv(tempDir)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, uvPath, "python", "list", "--output-format", "json")
+	cmd.Env = append(os.Environ(), uvEnv...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("uv python list failed: %w", err)
+	}
+	var installations []pythonInstallation
+	if err := json.Unmarshal(out, &installations); err != nil {
+		return nil, fmt.Errorf("failed to parse uv python list output: %w", err)
+	}
+	return installations, nil
+}
+
+// cache returns the cache of the uv that runs scripts.
+func (c *controller) cache() (*uvCache, error) {
+	c.mu.Lock()
+	uvPath, tempDir := c.uvPath, c.tempDir
+	c.mu.Unlock()
+	if uvPath == "" {
+		return nil, errors.New("uv is not set up")
+	}
+	env, err := c.config.uvEnv(tempDir)
+	if err != nil {
+		return nil, err
+	}
+	return &uvCache{cfg: c.config, uvPath: uvPath, env: append(os.Environ(), env...), logf: c.logf}, nil
+}
+
+// cleanCache prunes uv's cache or, with wipe, removes it in the background,
+// moving the session to stateCleaning until done.
+func (c *controller) cleanCache(wipe bool) error {
+	cache, err := c.cache()
+	if err != nil {
+		return err
+	}
+	if err := c.move(stateCleaning, nil); err != nil {
+		return err
+	}
+
+	go func() {
+		defer c.move(stateReady, nil)
+
+		ctx := context.Background()
+		if wipe {
+			c.logf("Wiping the uv cache...\n")
+			err = cache.wipe(ctx)
+		} else {
+			c.logf("Pruning the uv cache...\n")
+			err = cache.prune(ctx)
+		}
+		if err != nil {
+			c.logf("Error: %v\n", err)
+			return
+		}
+		if report, err := cache.report(ctx); err == nil {
+			c.logf("%s", report)
+		}
+	}()
+	return nil
+}
+
+// errNoScripts is returned by run when there is nothing to run.
+var errNoScripts = errors.New("no scripts to run")
+
+// run starts the scripts with uv in the background, moving the session to
+// stateRunning until they exit. python, when set, overrides the configured
+// Python, args are passed to the script that runs, and env is added to its
+// environment.
+func (c *controller) run(python string, args, env []string) error {
+	c.mu.Lock()
+	if len(c.scripts) == 0 {
+		c.mu.Unlock()
+		return errNoScripts
+	}
+	if err := c.moveLocked(stateRunning); err != nil {
+		c.mu.Unlock()
+		return fmt.Errorf("cannot run scripts while %s", c.state)
+	}
+	scripts := append([]string(nil), c.scripts...)
+	uvPath, tempDir := c.uvPath, c.tempDir
+
+	// Scripts of this session have all exited by the time it is ready
+	// again, but processes adopted from an earlier one may still run
+	adopted := c.adopted
+	c.adopted = nil
+	c.mu.Unlock()
+	c.onChange(stateRunning)
+	c.logf("Starting script execution...\n")
+
+	go func() {
+		defer c.move(stateReady, nil)
+
+		// Servers from an earlier session would hold on to the ports
+		if len(adopted) > 0 {
+			c.logf("Stopping processes from an earlier session...\n")
+			c.stopRecorded(adopted)
+		}
+
+		c.runScripts(uvPath, tempDir, python, scripts, args, env)
+	}()
+	return nil
+}
+
+// prepare resolves and caches the dependencies of every script in the
+// background, moving the session to statePreparing until done.
+func (c *controller) prepare() error {
+	c.mu.Lock()
+	if len(c.scripts) == 0 {
+		c.mu.Unlock()
+		return errNoScripts
+	}
+	if err := c.moveLocked(statePreparing); err != nil {
+		c.mu.Unlock()
+		return fmt.Errorf("cannot prepare scripts while %s", c.state)
+	}
+	scripts := append([]string(nil), c.scripts...)
+	uvPath, tempDir := c.uvPath, c.tempDir
+	c.mu.Unlock()
+	c.onChange(statePreparing)
+
+	go func() {
+		defer c.move(stateReady, nil)
+
+		client, err := c.config.httpClient()
+		if err != nil {
+			c.logf("Error configuring downloads: %v\n", err)
+			return
+		}
+		prepareScripts(context.Background(), newDownloader(client, c.logf), c.config, uvPath, tempDir, scripts, c.approve, c.logf)
+	}()
+	return nil
+}
+
+func (c *controller) runScripts(uvPath, tempDir, python string, scripts, scriptArgs, env []string) {
+	settings := c.config.script(scripts[0])
+	if python == "" {
+		python = c.config.python(scripts[0])
+	}
+
+	// Run verified local copies of remote scripts
+	client, err := c.config.httpClient()
+	if err != nil {
+		c.logf("Error configuring downloads: %v\n", err)
+		return
+	}
+	scripts, err = localScripts(context.Background(), newDownloader(client, c.logf), c.config, scripts, tempDir, c.approve)
+	if err != nil {
+		c.logf("Error: %v\n", err)
+		return
+	}
+
+	if source, err := os.ReadFile(scripts[0]); err == nil {
+		if metadata, err := parseScriptMetadata(source); err == nil && metadata != nil {
+			if err := checkRequiresPython(metadata.RequiresPython, python); err != nil {
+				c.logf("Error: %v\n", err)
+				return
+			}
+		}
+	}
+
+	// Build command: uv run <scripts...>, or uv tool run <tool...> with the
+	// entries after a tool as its arguments
+	var args []string
+	if tool := parseToolEntry(scripts[0]); tool != nil {
+		args = tool.runArgs(python, scripts[1:])
+	} else {
+		args = []string{"run"}
+		if python != "" {
+			args = append(args, "--python", python)
+		}
+		args = append(args, scripts...)
+	}
+	if c.payload != nil {
+		args = append(args, c.payload.manifest.Args...)
+	}
+	args = append(args, scriptArgs...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, uvPath, args...)
+	cmd.Env = append(os.Environ(), env...)
+
+	// Pass proxy and CA settings on to uv
+	uvEnv, err := c.config.uvEnv(tempDir)
+	if err != nil {
+		c.logf("Error configuring uv environment: %v\n", err)
+		return
+	}
+	cmd.Env = append(cmd.Env, uvEnv...)
+	if c.payload != nil {
+		cmd.Env = append(cmd.Env, c.payload.manifest.environ()...)
+	}
+
+	// Set up process group for proper cleanup on Unix systems
+	if runtime.GOOS != "windows" {
+		c.setupProcessGroup(cmd)
+	}
+
+	// Unless the script should outlive us, have it terminated when we die
+	if !settings.Detach {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		dieWithParent(cmd)
+	}
+
+	// Create pipes for stdout and stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		c.logf("Error creating stdout pipe: %v\n", err)
+		return
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		c.logf("Error creating stderr pipe: %v\n", err)
+		return
+	}
+
+	// Start the command
+	if err := cmd.Start(); err != nil {
+		c.logf("Error starting command: %v\n", err)
+		return
+	}
+
+	// Track the running command, also on disk should we crash. Detached
+	// scripts are left running when we exit.
+	if !settings.Detach {
+		c.mu.Lock()
+		c.running = append(c.running, cmd)
+		c.mu.Unlock()
+	}
+	record, err := recordProcess(cmd, runtime.GOOS != "windows", settings.Detach)
+	if err != nil {
+		c.logf("Warning: could not record process %d: %v\n", cmd.Process.Pid, err)
+	}
+	defer record.forget()
+
+	// Read output in goroutines
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		c.readOutput(stdout, "STDOUT")
+	}()
+
+	go func() {
+		defer wg.Done()
+		c.readOutput(stderr, "STDERR")
+	}()
+
+	// Wait for output readers to finish
+	wg.Wait()
+
+	// Wait for completion
+	if err := cmd.Wait(); err != nil {
+		c.logf("Command finished with error: %v\n", err)
+	} else {
+		c.logf("Scripts completed successfully!\n")
+	}
+
+	// Remove from tracking when completed, and clean up whatever the script
+	// left running
+	c.mu.Lock()
+	for i, other := range c.running {
+		if other == cmd {
+			c.running = append(c.running[:i], c.running[i+1:]...)
+			break
+		}
+	}
+	c.mu.Unlock()
+	if !settings.Detach {
+		reapGroup(cmd.Process.Pid)
+	}
+}
+
+func (c *controller) readOutput(reader io.Reader, prefix string) {
+	buf := make([]byte, 1024)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			c.output(string(buf[:n]))
+		}
+		if err != nil {
+			if err != io.EOF {
+				c.logf("%s read error: %v\n", prefix, err)
+			}
+			break
+		}
+	}
+}
+
+func (c *controller) setupProcessGroup(cmd *exec.Cmd) {
+	// On Unix systems, create a new process group so we can kill
+	// the entire group (including child processes) when needed
+	if runtime.GOOS != "windows" {
+		setupUnixProcessGroup(cmd)
+	}
+}
+
+// killCmd terminates a script and its children. When graceful is set, they
+// get a moment to exit on SIGTERM first.
+func killCmd(cmd *exec.Cmd, graceful bool) {
+	if cmd.Process == nil {
+		return
+	}
+	// On macOS/Unix, kill the process group to ensure child processes are terminated
+	if runtime.GOOS != "windows" {
+		// Kill the process group (negative PID kills the process group)
+		if err := exec.Command("kill", "-TERM", fmt.Sprintf("-%d", cmd.Process.Pid)).Run(); err == nil && graceful {
+			// Wait a bit for graceful termination
+			time.Sleep(100 * time.Millisecond)
+		}
+		if graceful {
+			// Force kill if still running
+			exec.Command("kill", "-KILL", fmt.Sprintf("-%d", cmd.Process.Pid)).Run()
+		}
+	} else {
+		// On Windows, use taskkill to terminate the process tree
+		exec.Command("taskkill", "/F", "/T", "/PID", fmt.Sprintf("%d", cmd.Process.Pid)).Run()
+	}
+
+	// Also try direct process kill as fallback
+	cmd.Process.Kill()
+}
+
+// adopt takes over a process from an earlier session: it is reported when it
+// exits and stopped along with this session's processes.
+func (c *controller) adopt(r *processRecord) {
+	if err := r.adopt(); err != nil {
+		c.logf("Warning: could not update record of PID %d: %v\n", r.PID, err)
+	}
+	c.mu.Lock()
+	c.adopted = append(c.adopted, r)
+	c.mu.Unlock()
+	c.logf("Reattached to %s\n", r)
+
+	go func() {
+		for r.running() {
+			time.Sleep(2 * time.Second)
+		}
+		c.mu.Lock()
+		tracked := false
+		for i, other := range c.adopted {
+			if other == r {
+				c.adopted = append(c.adopted[:i], c.adopted[i+1:]...)
+				tracked = true
+				break
+			}
+		}
+		c.mu.Unlock()
+		if tracked {
+			r.forget()
+			c.logf("Process %d from an earlier session has exited\n", r.PID)
+		}
+	}()
+}
+
+// stopRecorded terminates processes from an earlier session.
+func (c *controller) stopRecorded(records []*processRecord) {
+	for _, r := range records {
+		if err := r.stop(); err != nil {
+			c.logf("Could not stop PID %d: %v\n", r.PID, err)
+			continue
+		}
+		c.logf("Stopped PID %d\n", r.PID)
+	}
+}
+
+// shutdown stops all processes of the session and removes its files.
+func (c *controller) shutdown() {
+	c.cancelInitialize()
+
+	c.mu.Lock()
+	running, adopted, tempDir := c.running, c.adopted, c.tempDir
+	c.running, c.adopted = nil, nil
+	c.mu.Unlock()
+
+	for _, cmd := range running {
+		killCmd(cmd, true)
+	}
+	c.stopRecorded(adopted)
+
+	// Clean up temp directory if it exists
+	if tempDir != "" {
+		os.RemoveAll(tempDir)
+	}
+}
@@ -0,0 +1,219 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUV stands in for uv: it reports a version and echoes what it is asked
+// to run, after sleeping for $FAKE_UV_SLEEP seconds.
+const fakeUV = `#!/bin/sh
+if [ "$1" = "--version" ]; then
+	echo "uv 0.9.9"
+	exit 0
+fi
+sleep "${FAKE_UV_SLEEP:-0}"
+echo "ran $*"
+`
+
+// testSession is a controller whose uv is fakeUV, with its output and state
+// changes collected.
+type testSession struct {
+	*controller
+	mu     sync.Mutex
+	output strings.Builder
+}
+
+func newTestSession(t *testing.T, scripts ...string) *testSession {
+	t.Helper()
+	dir := t.TempDir()
+	binDir := filepath.Join(dir, "path")
+	if err := os.Mkdir(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(binDir, "uv"), []byte(fakeUV), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+
+	cfg := &Config{BinDir: filepath.Join(dir, "run"), UVConstraint: ">=0.1"}
+	s := &testSession{}
+	s.controller = newController(cfg, nil, scripts, func(text string) {
+		s.mu.Lock()
+		s.output.WriteString(text)
+		s.mu.Unlock()
+	}, func(sessionState) {}, func(*scriptReview) bool { return false })
+	t.Cleanup(s.shutdown)
+	return s
+}
+
+func (s *testSession) log() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.output.String()
+}
+
+// waitFor polls until the session reaches want.
+func (s *testSession) waitFor(t *testing.T, want sessionState) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if state, _ := s.current(); state == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	state, err := s.current()
+	t.Fatalf("session is %s (%v), want %s\noutput:\n%s", state, err, want, s.log())
+}
+
+func TestControllerRun(t *testing.T) {
+	s := newTestSession(t, "hello.py")
+	if err := s.initialize(); err != nil {
+		t.Fatal(err)
+	}
+	s.waitFor(t, stateReady)
+
+	if err := s.run("", []string{"--name", "x"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	s.waitFor(t, stateReady)
+	if !strings.Contains(s.log(), "ran run hello.py --name x") {
+		t.Errorf("script did not run:\n%s", s.log())
+	}
+}
+
+func TestControllerRunRefusedUntilReady(t *testing.T) {
+	s := newTestSession(t)
+	if err := s.run("", nil, nil); !errors.Is(err, errNoScripts) {
+		t.Errorf("run() without scripts = %v", err)
+	}
+	s.addScript("hello.py")
+	if err := s.run("", nil, nil); err == nil {
+		t.Error("run() succeeded while initializing")
+	}
+
+	if err := s.initialize(); err != nil {
+		t.Fatal(err)
+	}
+	s.waitFor(t, stateReady)
+	if err := s.run("", nil, []string{"FAKE_UV_SLEEP=30"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.run("", nil, nil); err == nil {
+		t.Error("second run() succeeded while running")
+	}
+	if err := s.prepare(); err == nil {
+		t.Error("prepare() succeeded while running")
+	}
+
+	// Shutting down kills the script, which ends the run
+	start := time.Now()
+	s.shutdown()
+	s.waitFor(t, stateReady)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("script survived shutdown for %s", elapsed)
+	}
+}
+
+func TestControllerCancelAndRetry(t *testing.T) {
+	// A proxy that never answers keeps the download of uv pending
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer proxy.Close()
+
+	s := newTestSession(t, "hello.py")
+	s.config.UVConstraint = ">=99"
+	s.config.Proxy = proxy.URL
+	if err := s.initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.initialize(); err == nil {
+		t.Error("second initialize() succeeded while setting up")
+	}
+	s.cancelInitialize()
+	s.waitFor(t, stateFailed)
+	if _, err := s.current(); !errors.Is(err, errSetUpCancelled) {
+		t.Errorf("failure reason = %v, want %v", err, errSetUpCancelled)
+	}
+
+	s.config.UVConstraint = ">=0.1"
+	if err := s.initialize(); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	s.waitFor(t, stateReady)
+}
+
+// TestControllerConcurrent drives a session from many goroutines at once,
+// as the GUI's buttons and background work do; run it with -race.
+func TestControllerConcurrent(t *testing.T) {
+	s := newTestSession(t, "a.py", "b.py")
+	if err := s.initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			for j := range 20 {
+				switch (i + j) % 6 {
+				case 0:
+					s.initialize()
+				case 1:
+					s.cancelInitialize()
+				case 2:
+					if err := s.run("", nil, nil); err != nil && !strings.Contains(err.Error(), "cannot run") {
+						t.Errorf("run() = %v", err)
+					}
+				case 3:
+					s.addScript("c.py")
+				case 4:
+					s.removeScript(s.scriptCount() - 1)
+				case 5:
+					s.current()
+					s.scriptAt(0)
+					s.uv()
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+	wg.Wait()
+
+	// Whatever happened, the session settles and can run scripts
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		state, _ := s.current()
+		if state == stateFailed {
+			if err := s.initialize(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if state == stateReady || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.waitFor(t, stateReady)
+	s.setScripts([]string{"final.py"})
+	if err := s.run("", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	s.waitFor(t, stateReady)
+	if !strings.Contains(s.log(), "ran run final.py") {
+		t.Errorf("final run missing:\n%s", s.log())
+	}
+}
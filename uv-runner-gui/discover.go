@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// systemUVCandidates lists where an existing uv is looked for, in order of
+// preference: PATH, then the standalone installer's and cargo's locations.
+func systemUVCandidates() []string {
+	exe := "uv"
+	if runtime.GOOS == "windows" {
+		exe = "uv.exe"
+	}
+
+	var candidates []string
+	if path, err := exec.LookPath("uv"); err == nil {
+		candidates = append(candidates, path)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates,
+			filepath.Join(home, ".local", "bin", exe),
+			filepath.Join(home, ".cargo", "bin", exe),
+		)
+	}
+	return candidates
+}
+
+// uvBinaryVersion runs `uv --version`, which prints e.g.
+// "uv 0.9.5 (d5f39331a 2025-10-21)".
+func uvBinaryVersion(path string) (semver, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return semver{}, err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 || fields[0] != "uv" {
+		return semver{}, fmt.Errorf("unexpected version output %q", strings.TrimSpace(string(out)))
+	}
+	return parseSemver(fields[1])
+}
+
+// findSystemUV returns the first installed uv whose version satisfies the
+// constraint, or "" when there is none.
+func findSystemUV(constraint string, logf logFunc) (string, error) {
+	allowed, err := parseConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+
+	seen := map[string]bool{}
+	for _, candidate := range systemUVCandidates() {
+		if resolved, err := filepath.EvalSymlinks(candidate); err == nil {
+			candidate = resolved
+		}
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+
+		if info, err := os.Stat(candidate); err != nil || info.IsDir() {
+			continue
+		}
+		version, err := uvBinaryVersion(candidate)
+		if err != nil {
+			logf("Ignoring %s: %v\n", candidate, err)
+			continue
+		}
+		if !allowed.allows(version) {
+			logf("Ignoring %s: version %s does not satisfy %q\n", candidate, version, constraint)
+			continue
+		}
+		return candidate, nil
+	}
+	return "", nil
+}
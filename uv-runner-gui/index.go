@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// IndexCredentials authenticate uv with a package index.
+type IndexCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+// indexEnv returns the environment variables that point uv at the
+// configured package indexes. Each index is given a name so that its
+// credentials can be passed as UV_INDEX_<NAME>_USERNAME and _PASSWORD
+// rather than in the URL, where they would show up in uv's output.
+func (c *Config) indexEnv() ([]string, error) {
+	var env []string
+	index := func(name, raw string) (string, error) {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("invalid index URL %q", redact(raw))
+		}
+		if creds, ok := c.IndexCredentials[u.Host]; ok {
+			prefix := "UV_INDEX_" + strings.ToUpper(name) + "_"
+			env = append(env, prefix+"USERNAME="+creds.Username, prefix+"PASSWORD="+creds.Password)
+		}
+		return name + "=" + raw, nil
+	}
+
+	var extras []string
+	for i, raw := range c.ExtraIndexes {
+		extra, err := index(fmt.Sprintf("extra%d", i+1), raw)
+		if err != nil {
+			return nil, err
+		}
+		extras = append(extras, extra)
+	}
+	if len(extras) > 0 {
+		env = append(env, "UV_INDEX="+strings.Join(extras, " "))
+	}
+	if c.Index != "" {
+		def, err := index("default", c.Index)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, "UV_DEFAULT_INDEX="+def)
+	}
+	return env, nil
+}
+
+// secrets returns the passwords in the configuration, for redact.
+func (c *Config) secrets() []string {
+	var secrets []string
+	if c.ProxyPassword != "" {
+		secrets = append(secrets, c.ProxyPassword)
+	}
+	for _, creds := range c.IndexCredentials {
+		if creds.Password != "" {
+			secrets = append(secrets, creds.Password)
+		}
+	}
+	return secrets
+}
+
+// urlUserInfo matches the user information of URLs in text.
+var urlUserInfo = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://)[^/\s@]+@`)
+
+// redact hides credentials in URLs, and any of secrets, in text.
+func redact(text string, secrets ...string) string {
+	text = urlUserInfo.ReplaceAllString(text, "${1}****@")
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, "****")
+	}
+	return text
+}
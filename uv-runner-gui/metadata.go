@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ScriptMetadata is the inline metadata of a script as specified by PEP 723:
+// a TOML document in a comment block starting with "# /// script" and ending
+// with "# ///".
+type ScriptMetadata struct {
+	RequiresPython string         `toml:"requires-python"`
+	Dependencies   []string       `toml:"dependencies"`
+	Tool           map[string]any `toml:"tool"`
+
+	// Runner is the [tool.uv-runner] table, which is also part of Tool.
+	Runner RunnerMetadata `toml:"-"`
+}
+
+// RunnerMetadata holds the settings a script declares for uv-runner.
+type RunnerMetadata struct {
+	Parameters []ScriptParameter `toml:"parameters"`
+}
+
+// parseScriptMetadata extracts the inline metadata of a script, returning nil
+// when it has none.
+func parseScriptMetadata(source []byte) (*ScriptMetadata, error) {
+	lines := strings.Split(strings.ReplaceAll(string(source), "\r\n", "\n"), "\n")
+
+	var block []string
+	for i := 0; i < len(lines); i++ {
+		if lines[i] != "# /// script" {
+			continue
+		}
+		if block != nil {
+			return nil, fmt.Errorf("line %d: multiple script metadata blocks", i+1)
+		}
+
+		// As in the reference implementation, the block ends at the last
+		// "# ///" of the comment lines that follow
+		end := -1
+		for j := i + 1; j < len(lines) && (lines[j] == "#" || strings.HasPrefix(lines[j], "# ")); j++ {
+			if lines[j] == "# ///" {
+				end = j
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unterminated script metadata block", i+1)
+		}
+		block = []string{}
+		for _, line := range lines[i+1 : end] {
+			block = append(block, strings.TrimPrefix(strings.TrimPrefix(line, "#"), " "))
+		}
+		i = end
+	}
+	if block == nil {
+		return nil, nil
+	}
+
+	document := strings.Join(block, "\n")
+	metadata := &ScriptMetadata{}
+	if _, err := toml.Decode(document, metadata); err != nil {
+		return nil, fmt.Errorf("invalid script metadata: %w", err)
+	}
+	var tool struct {
+		Tool struct {
+			Runner RunnerMetadata `toml:"uv-runner"`
+		} `toml:"tool"`
+	}
+	if _, err := toml.Decode(document, &tool); err != nil {
+		return nil, fmt.Errorf("invalid [tool.uv-runner] table: %w", err)
+	}
+	metadata.Runner = tool.Tool.Runner
+	if err := metadata.Runner.validate(); err != nil {
+		return nil, fmt.Errorf("invalid [tool.uv-runner] table: %w", err)
+	}
+	return metadata, nil
+}
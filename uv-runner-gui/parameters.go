@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Parameter types.
+const (
+	paramString = "string"
+	paramInt    = "int"
+	paramFloat  = "float"
+	paramBool   = "bool"
+	paramFile   = "file"
+)
+
+// ScriptParameter is an input a script declares in its [tool.uv-runner]
+// table, for example:
+//
+//	[[tool.uv-runner.parameters]]
+//	name = "port"
+//	type = "int"
+//	default = 8000
+//	help = "Port to listen on"
+//
+// Values are passed as "--<name> <value>" arguments, or with Flag as
+// "<flag> <value>"; a bool parameter passes just the flag when true. With Env
+// set, the value goes into that environment variable instead.
+type ScriptParameter struct {
+	Name    string   `toml:"name"`
+	Type    string   `toml:"type"`
+	Default any      `toml:"default"`
+	Choices []string `toml:"choices"`
+	Help    string   `toml:"help"`
+	Flag    string   `toml:"flag"`
+	Env     string   `toml:"env"`
+}
+
+func (m *RunnerMetadata) validate() error {
+	seen := map[string]bool{}
+	for i := range m.Parameters {
+		p := &m.Parameters[i]
+		if p.Name == "" {
+			return fmt.Errorf("parameter #%d has no name", i+1)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("parameter %q is declared twice", p.Name)
+		}
+		seen[p.Name] = true
+
+		switch p.Type {
+		case "":
+			p.Type = paramString
+		case paramString, paramInt, paramFloat, paramBool, paramFile:
+		default:
+			return fmt.Errorf("parameter %q has unknown type %q", p.Name, p.Type)
+		}
+		if len(p.Choices) > 0 && p.Type == paramBool {
+			return fmt.Errorf("bool parameter %q cannot have choices", p.Name)
+		}
+		if p.Default != nil {
+			if err := p.check(p.defaultValue()); err != nil {
+				return fmt.Errorf("default of %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// defaultValue returns the default as a string, "" when there is none.
+func (p *ScriptParameter) defaultValue() string {
+	switch v := p.Default.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// check validates a value given for the parameter.
+func (p *ScriptParameter) check(value string) error {
+	var err error
+	switch p.Type {
+	case paramInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case paramFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case paramBool:
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("parameter %q: %q is not a valid %s", p.Name, value, p.Type)
+	}
+	if len(p.Choices) > 0 && !slices.Contains(p.Choices, value) {
+		return fmt.Errorf("parameter %q: %q is not one of %s", p.Name, value, strings.Join(p.Choices, ", "))
+	}
+	return nil
+}
+
+// flagName returns the command line flag the parameter is passed as.
+func (p *ScriptParameter) flagName() string {
+	if p.Flag != "" {
+		return p.Flag
+	}
+	return "--" + p.Name
+}
+
+// parameterArgs turns parameter values, keyed by name, into arguments and
+// environment variables for the script. Parameters without a value use their
+// default, and are left out when they have none.
+func parameterArgs(params []ScriptParameter, values map[string]string) (args, env []string, err error) {
+	for i := range params {
+		p := &params[i]
+		value, ok := values[p.Name]
+		if !ok || value == "" {
+			value = p.defaultValue()
+		}
+		if value == "" {
+			continue
+		}
+		if err := p.check(value); err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case p.Env != "":
+			env = append(env, p.Env+"="+value)
+		case p.Type == paramBool:
+			if on, _ := strconv.ParseBool(value); on {
+				args = append(args, p.flagName())
+			}
+		default:
+			args = append(args, p.flagName(), value)
+		}
+	}
+	return args, env, nil
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A payload is a zip archive appended to the uv-runner executable, followed
+// by a footer holding the archive's length and payloadMagic. It carries a
+// manifest.json describing its contents, optionally a uv release archive and
+// any Python scripts, so the binary can run without network access.
+const (
+	payloadMagic      = "UVRPAYL1"
+	payloadFooterSize = 8 + len(payloadMagic)
+	payloadManifest   = "manifest.json"
+)
+
+// Manifest is the manifest.json stored in a payload. Besides the embedded
+// files it carries the launcher's branding and run configuration.
+type Manifest struct {
+	// Name replaces "UV Runner" as the application name; Icon names a PNG
+	// in the payload used as the GUI's window icon.
+	Name string `json:"name,omitempty"`
+	Icon string `json:"icon,omitempty"`
+
+	// Scripts is the default script list. Entries naming a file in the
+	// payload are extracted and run from disk; anything else (URLs, paths
+	// on the target machine) is passed to uv unchanged.
+	Scripts []string `json:"scripts,omitempty"`
+
+	// Args are passed after the embedded scripts, and Env is added to the
+	// environment uv runs them in.
+	Args []string          `json:"args,omitempty"`
+	Env  map[string]string `json:"env,omitempty"`
+
+	// UVVersion pins the uv release the launcher installs.
+	UVVersion string `json:"uv_version,omitempty"`
+
+	// Index and ExtraIndexes replace the user's package indexes. They never
+	// carry credentials, which come from the user's index_credentials.
+	Index        string   `json:"index,omitempty"`
+	ExtraIndexes []string `json:"extra_indexes,omitempty"`
+
+	// UV describes the embedded uv release archive, if any.
+	UV *EmbeddedUV `json:"uv,omitempty"`
+}
+
+// applyTo makes the launcher's settings take precedence over the user's.
+func (m *Manifest) applyTo(cfg *Config) {
+	if m.UVVersion != "" {
+		cfg.UVVersion = m.UVVersion
+	}
+	if m.Index != "" {
+		cfg.Index = m.Index
+	}
+	if len(m.ExtraIndexes) > 0 {
+		cfg.ExtraIndexes = m.ExtraIndexes
+	}
+}
+
+// environ returns Env as KEY=value pairs.
+func (m *Manifest) environ() []string {
+	var env []string
+	for k, v := range m.Env {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// EmbeddedUV identifies a uv release archive stored in the payload.
+type EmbeddedUV struct {
+	Version string `json:"version"`
+	Target  string `json:"target"`
+	File    string `json:"file"`
+	SHA256  string `json:"sha256"`
+}
+
+type payload struct {
+	zip      *zip.Reader
+	file     *os.File
+	start    int64 // offset of the payload, i.e. size of the executable proper
+	manifest Manifest
+}
+
+// openPayload reads the payload appended to the running executable. It
+// returns nil without error when there is none.
+func openPayload() (*payload, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(exe)
+	if err != nil {
+		return nil, err
+	}
+	p, err := readPayload(f)
+	if p == nil {
+		f.Close()
+	}
+	return p, err
+}
+
+// readPayload looks for a payload at the end of f.
+func readPayload(f *os.File) (*payload, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size < int64(payloadFooterSize) {
+		return nil, nil
+	}
+
+	footer := make([]byte, payloadFooterSize)
+	if _, err := f.ReadAt(footer, size-int64(payloadFooterSize)); err != nil {
+		return nil, err
+	}
+	if string(footer[8:]) != payloadMagic {
+		return nil, nil
+	}
+	length := int64(binary.BigEndian.Uint64(footer[:8]))
+	start := size - int64(payloadFooterSize) - length
+	if length <= 0 || start < 0 {
+		return nil, fmt.Errorf("corrupt payload footer")
+	}
+
+	zr, err := zip.NewReader(io.NewSectionReader(f, start, length), length)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+	p := &payload{zip: zr, file: f, start: start}
+
+	rc, err := zr.Open(payloadManifest)
+	if err != nil {
+		return nil, fmt.Errorf("payload has no %s: %w", payloadManifest, err)
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(&p.manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse payload manifest: %w", err)
+	}
+	return p, nil
+}
+
+// executableSize returns the size of f without any payload.
+func executableSize(f *os.File) (int64, error) {
+	p, err := readPayload(f)
+	if err != nil {
+		return 0, err
+	}
+	if p != nil {
+		return p.start, nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (p *payload) Close() error {
+	return p.file.Close()
+}
+
+// has reports whether the payload contains a file with the given name.
+func (p *payload) has(name string) bool {
+	f, err := p.zip.Open(name)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// extractTo copies a payload file to w.
+func (p *payload) extractTo(name string, w io.Writer) error {
+	rc, err := p.zip.Open(name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+// scripts returns the manifest's script list with embedded scripts written
+// to dir and replaced by their paths there.
+func (p *payload) scripts(dir string) ([]string, error) {
+	var scripts []string
+	for _, entry := range p.manifest.Scripts {
+		if !p.has(entry) {
+			scripts = append(scripts, entry)
+			continue
+		}
+		if !filepath.IsLocal(entry) {
+			return nil, fmt.Errorf("payload script %q escapes the extraction directory", entry)
+		}
+		dest := filepath.Join(dir, "payload", filepath.FromSlash(entry))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return nil, err
+		}
+		out, err := os.Create(dest)
+		if err != nil {
+			return nil, err
+		}
+		err = p.extractTo(entry, out)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", entry, err)
+		}
+		scripts = append(scripts, dest)
+	}
+	return scripts, nil
+}
+
+// embeddedUV returns the payload's uv archive description if it matches the
+// version and target being installed.
+func (p *payload) embeddedUV(version, target string) *EmbeddedUV {
+	if p == nil {
+		return nil
+	}
+	uv := p.manifest.UV
+	if uv == nil || uv.Version != version || uv.Target != target || !p.has(uv.File) {
+		return nil
+	}
+	return uv
+}
+
+// copyEmbeddedUV writes the embedded uv archive into f and verifies it. The
+// digest the manifest records only guards against a damaged payload, as
+// whoever built the payload chose it; the archive then gets the same checks
+// as a download (pinned digest, published checksum, signed manifest). Without
+// network access that requires a pinned digest for the version and target.
+func (p *payload) copyEmbeddedUV(ctx context.Context, dl *downloader, cfg *Config, uv *EmbeddedUV, f *os.File) error {
+	if err := p.extractTo(uv.File, f); err != nil {
+		return fmt.Errorf("failed to extract embedded uv: %w", err)
+	}
+	actual, err := fileSHA256(f)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, uv.SHA256) {
+		return fmt.Errorf("embedded uv archive is damaged: expected %s, got %s", uv.SHA256, actual)
+	}
+	if err := verifyArchive(ctx, dl, cfg, f, uv.Version, uv.Target); err != nil {
+		return fmt.Errorf("cannot verify embedded uv %s (%s): %w", uv.Version, uv.Target, err)
+	}
+	return nil
+}
+
+// writePayload appends a payload built from manifest and files (payload
+// name to content) to w, which should already hold the executable.
+func writePayload(w io.Writer, manifest Manifest, files map[string][]byte) error {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if files == nil {
+		files = map[string][]byte{}
+	}
+	files[payloadManifest] = data
+
+	for name, content := range files {
+		if path.IsAbs(name) || strings.Contains(name, "\\") || !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid payload file name %q", name)
+		}
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(content); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	footer := make([]byte, payloadFooterSize)
+	binary.BigEndian.PutUint64(footer[:8], uint64(buf.Len()))
+	copy(footer[8:], payloadMagic)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	_, err = w.Write(footer)
+	return err
+}
+
+// sha256Hex returns the hex digest of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%x", sum)
+}
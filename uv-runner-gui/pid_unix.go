@@ -0,0 +1,41 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// processAlive reports whether a process with the given ID exists.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// stopGracePeriod is how long a process may take to exit after SIGTERM
+// before it is killed.
+const stopGracePeriod = 5 * time.Second
+
+// terminateProcess asks a process, or its whole group when pgid is set, to
+// exit and kills it if it has not done so within stopGracePeriod.
+func terminateProcess(pid, pgid int) error {
+	target := pid
+	if pgid > 0 {
+		target = -pgid
+	}
+	if err := syscall.Kill(target, syscall.SIGTERM); err != nil {
+		return err
+	}
+	for deadline := time.Now().Add(stopGracePeriod); time.Now().Before(deadline); {
+		if !processAlive(pid) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err := syscall.Kill(target, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
@@ -7,7 +7,7 @@ import (
 	"syscall"
 )
 
-func (a *App) setupUnixProcessGroup(cmd *exec.Cmd) {
+func setupUnixProcessGroup(cmd *exec.Cmd) {
 	if cmd.SysProcAttr == nil {
 		cmd.SysProcAttr = &syscall.SysProcAttr{}
 	}
@@ -6,7 +6,7 @@ import (
 	"os/exec"
 )
 
-func (a *App) setupUnixProcessGroup(cmd *exec.Cmd) {
+func setupUnixProcessGroup(cmd *exec.Cmd) {
 	// On Windows, we don't use process groups the same way
 	// Process cleanup is handled differently in the cleanup() function
 }
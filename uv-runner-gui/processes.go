@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processRecord describes a process started by uv-runner. One record file per
+// process is kept in the data directory for as long as the process runs, so a
+// later uv-runner can find servers orphaned by a crash.
+type processRecord struct {
+	PID int `json:"pid"`
+
+	// PGID is the process group the process leads, or 0 when it shares
+	// uv-runner's group.
+	PGID int `json:"pgid,omitempty"`
+
+	// Owner is the uv-runner process responsible for it.
+	Owner int `json:"owner"`
+
+	Started time.Time `json:"started"`
+	Command []string  `json:"command"`
+
+	// Detached is set for scripts meant to outlive uv-runner; they are not
+	// reported as orphans.
+	Detached bool `json:"detached,omitempty"`
+
+	// Identity distinguishes the process from a later one that reuses its
+	// PID, where the platform offers a way to tell; see processIdentity.
+	Identity string `json:"identity,omitempty"`
+}
+
+func processStateDir() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "processes"), nil
+}
+
+func (r *processRecord) path() (string, error) {
+	dir, err := processStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strconv.Itoa(r.PID)+".json"), nil
+}
+
+func (r *processRecord) save() error {
+	file, err := r.path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// forget removes the record once the process has exited or been stopped.
+func (r *processRecord) forget() {
+	if file, err := r.path(); err == nil {
+		os.Remove(file)
+	}
+}
+
+// String describes the process for listings.
+func (r *processRecord) String() string {
+	detached := ""
+	if r.Detached {
+		detached = " (detached)"
+	}
+	return fmt.Sprintf("PID %d%s, started %s: %s", r.PID, detached, r.Started.Local().Format(time.DateTime), strings.Join(r.Command, " "))
+}
+
+// recordProcess stores a record for a command that has just been started.
+// groupLeader tells whether it was started in a process group of its own.
+func recordProcess(cmd *exec.Cmd, groupLeader, detached bool) (*processRecord, error) {
+	pid := cmd.Process.Pid
+	r := &processRecord{
+		PID:      pid,
+		Owner:    os.Getpid(),
+		Started:  time.Now(),
+		Command:  cmd.Args,
+		Detached: detached,
+		Identity: processIdentity(pid),
+	}
+	if groupLeader {
+		r.PGID = pid
+	}
+	return r, r.save()
+}
+
+// running reports whether the recorded process still exists, as opposed to a
+// new process that happens to have the same PID.
+func (r *processRecord) running() bool {
+	if !processAlive(r.PID) {
+		return false
+	}
+	if r.Identity != "" {
+		if current := processIdentity(r.PID); current != "" && current != r.Identity {
+			return false
+		}
+	}
+	return true
+}
+
+// adopt makes the current uv-runner responsible for the process.
+func (r *processRecord) adopt() error {
+	r.Owner = os.Getpid()
+	return r.save()
+}
+
+// stop terminates the process, and the rest of its group if it leads one, and
+// removes its record.
+func (r *processRecord) stop() error {
+	if err := terminateProcess(r.PID, r.PGID); err != nil && r.running() {
+		return err
+	}
+	r.forget()
+	return nil
+}
+
+// survivingProcesses returns the recorded processes that are still running
+// but whose owner is gone, including detached ones unless orphansOnly is set.
+// Records of processes that have exited are removed.
+func survivingProcesses(orphansOnly bool) ([]*processRecord, error) {
+	dir, err := processStateDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var survivors []*processRecord
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r := &processRecord{}
+		if err := json.Unmarshal(data, r); err != nil || r.PID <= 0 {
+			os.Remove(file)
+			continue
+		}
+		switch {
+		case !r.running():
+			r.forget()
+		case r.Owner != os.Getpid() && processAlive(r.Owner):
+			// Still looked after by another uv-runner
+		case r.Detached && orphansOnly:
+		default:
+			survivors = append(survivors, r)
+		}
+	}
+	return survivors, nil
+}
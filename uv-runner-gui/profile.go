@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// validProfile matches profile names, which become directory names.
+var validProfile = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// profileDir returns the directory holding the uv cache and Python
+// installations of the configured profile, or "" when no profile is set and
+// uv uses the user's own.
+func (c *Config) profileDir() (string, error) {
+	if c.Profile == "" {
+		return "", nil
+	}
+	if !validProfile.MatchString(c.Profile) {
+		return "", fmt.Errorf("invalid profile name %q", c.Profile)
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "profiles", c.Profile), nil
+}
+
+// profileEnv returns the environment variables that keep uv's cache and
+// Python installations within the profile.
+func (c *Config) profileEnv() ([]string, error) {
+	dir, err := c.profileDir()
+	if err != nil || dir == "" {
+		return nil, err
+	}
+	return []string{
+		"UV_CACHE_DIR=" + filepath.Join(dir, "cache"),
+		"UV_PYTHON_INSTALL_DIR=" + filepath.Join(dir, "python"),
+	}, nil
+}
+
+// uvCache manages the cache of the uv that runs scripts, and the Python
+// installations of the profile, if one is set.
+type uvCache struct {
+	cfg    *Config
+	uvPath string
+	env    []string // The environment uv runs scripts in
+	logf   logFunc
+}
+
+// report describes where the cache is and how much space it takes up.
+func (u *uvCache) report(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, u.uvPath, "cache", "dir")
+	cmd.Env = u.env
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("uv cache dir failed: %w", err)
+	}
+	dir := strings.TrimSpace(string(out))
+
+	var report strings.Builder
+	if u.cfg.Profile != "" {
+		fmt.Fprintf(&report, "Profile: %s\n", u.cfg.Profile)
+	}
+	fmt.Fprintf(&report, "uv cache: %s (%s)\n", dir, formatSize(dirSize(dir)))
+	if profile, err := u.cfg.profileDir(); err == nil && profile != "" {
+		python := filepath.Join(profile, "python")
+		fmt.Fprintf(&report, "Python installations: %s (%s)\n", python, formatSize(dirSize(python)))
+	}
+	return report.String(), nil
+}
+
+// prune removes cache entries uv no longer uses.
+func (u *uvCache) prune(ctx context.Context) error {
+	return u.run(ctx, "cache", "prune")
+}
+
+// wipe removes the whole cache and, for a profile, its Python
+// installations, which uv downloads again when needed.
+func (u *uvCache) wipe(ctx context.Context) error {
+	if err := u.run(ctx, "cache", "clean"); err != nil {
+		return err
+	}
+	profile, err := u.cfg.profileDir()
+	if err != nil || profile == "" {
+		return err
+	}
+	u.logf("Removing Python installations of profile %s\n", u.cfg.Profile)
+	return os.RemoveAll(filepath.Join(profile, "python"))
+}
+
+func (u *uvCache) run(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, u.uvPath, args...)
+	cmd.Env = u.env
+	cmd.Stdout = logWriter(u.logf)
+	cmd.Stderr = logWriter(u.logf)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("uv %s failed: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// dirSize returns the total size of the files below dir, skipping any that
+// cannot be read.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// formatSize shows a byte count in binary units.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
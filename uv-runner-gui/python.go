@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pythonRequestPattern matches the uv Python requests that name a version,
+// such as "3.12", "3.13t", "cpython@3.12", "pypy3.10" or
+// "cpython-3.12.4-linux-x86_64-gnu".
+var pythonRequestPattern = regexp.MustCompile(`^(?:[a-z]+[@-]?)?(\d+(?:\.\d+){0,2})t?(?:[-+].*)?$`)
+
+// pythonVersion is a Python release number of up to three components. When
+// it has fewer, it stands for every release that starts with them.
+type pythonVersion []int
+
+func parsePythonVersion(s string) (pythonVersion, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid Python version %q", s)
+	}
+	v := make(pythonVersion, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid Python version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// padded returns the version with three components.
+func (v pythonVersion) padded() [3]int {
+	var p [3]int
+	copy(p[:], v)
+	return p
+}
+
+// next returns the first release after those v stands for.
+func (v pythonVersion) next() [3]int {
+	p := pythonVersion(append([]int(nil), v...))
+	p[len(p)-1]++
+	return p.padded()
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// hasPrefix reports whether the first components of v and prefix agree,
+// up to the shorter of the two.
+func (v pythonVersion) hasPrefix(prefix pythonVersion) bool {
+	for i := 0; i < len(v) && i < len(prefix); i++ {
+		if v[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// allows reports whether some release v stands for satisfies a single
+// requires-python clause such as ">=3.10" or "==3.12.*".
+func (v pythonVersion) allows(op string, want pythonVersion, wildcard bool) bool {
+	exact := len(v) == 3
+	lo, hi, w := v.padded(), v.next(), want.padded()
+	switch op {
+	case "==", "===":
+		if wildcard {
+			return v.hasPrefix(want)
+		}
+		if exact {
+			return compareVersions(lo, w) == 0
+		}
+		return compareVersions(lo, w) <= 0 && compareVersions(w, hi) < 0
+	case "!=":
+		if wildcard {
+			return len(v) < len(want) || !v.hasPrefix(want)
+		}
+		return !exact || compareVersions(lo, w) != 0
+	case ">=":
+		if exact {
+			return compareVersions(lo, w) >= 0
+		}
+		return compareVersions(w, hi) < 0
+	case ">":
+		if exact {
+			return compareVersions(lo, w) > 0
+		}
+		return compareVersions(w, hi) < 0
+	case "<=":
+		return compareVersions(lo, w) <= 0
+	case "<":
+		return compareVersions(lo, w) < 0
+	case "~=":
+		if len(want) < 2 {
+			return true
+		}
+		return v.allows(">=", want, false) && v.allows("==", want[:len(want)-1], true)
+	}
+	return true
+}
+
+// checkRequiresPython reports an error when the Python named by request
+// cannot satisfy a script's requires-python specifier. Requests without a
+// version, such as a path to an interpreter, and clauses this check does
+// not understand pass; uv has the final say.
+func checkRequiresPython(requires, request string) error {
+	m := pythonRequestPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(request)))
+	if requires == "" || m == nil {
+		return nil
+	}
+	version, err := parsePythonVersion(m[1])
+	if err != nil {
+		return nil
+	}
+
+	for _, clause := range strings.Split(requires, ",") {
+		clause = strings.TrimSpace(clause)
+		op := ""
+		for _, candidate := range []string{"===", "~=", "==", "!=", "<=", ">=", "<", ">"} {
+			if strings.HasPrefix(clause, candidate) {
+				op = candidate
+				break
+			}
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(clause, op))
+		wildcard := strings.HasSuffix(rest, ".*")
+		want, err := parsePythonVersion(strings.TrimSuffix(rest, ".*"))
+		if op == "" || err != nil {
+			continue
+		}
+		if !version.allows(op, want, wildcard) {
+			return fmt.Errorf("Python %s does not satisfy the script's requires-python %q", request, requires)
+		}
+	}
+	return nil
+}
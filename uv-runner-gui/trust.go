@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// trustStore remembers the content of each remote script the user approved,
+// so that a script is only run unreviewed while it stays the same. Approved
+// contents are kept next to the index for showing what changed.
+type trustStore struct {
+	dir     string
+	Scripts map[string]trustedScript `json:"scripts"`
+}
+
+type trustedScript struct {
+	SHA256   string    `json:"sha256"`
+	Approved time.Time `json:"approved"`
+}
+
+// scriptReview is what the user needs to see to approve a remote script:
+// its whole content when it is new, or how it changed since it was approved.
+type scriptReview struct {
+	URL    string
+	SHA256 string
+
+	// Previous is the digest of the approved content, or "" for a script
+	// that was never approved.
+	Previous string
+
+	// Text is the script itself, or a unified diff against the approved
+	// content.
+	Text string
+}
+
+// Title summarizes the review.
+func (r *scriptReview) Title() string {
+	if r.Previous == "" {
+		return fmt.Sprintf("%s has not been run before", r.URL)
+	}
+	return fmt.Sprintf("%s has changed since it was approved", r.URL)
+}
+
+func loadTrustStore() (*trustStore, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return nil, err
+	}
+	store := &trustStore{dir: filepath.Join(dir, "trusted"), Scripts: map[string]trustedScript{}}
+	data, err := os.ReadFile(store.indexPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", store.indexPath(), err)
+	}
+	if store.Scripts == nil {
+		store.Scripts = map[string]trustedScript{}
+	}
+	return store, nil
+}
+
+func (t *trustStore) indexPath() string {
+	return filepath.Join(t.dir, "trusted.json")
+}
+
+func (t *trustStore) contentPath(sum string) string {
+	return filepath.Join(t.dir, sum)
+}
+
+// review returns what must be approved before script may run, or nil when
+// this content was approved before.
+func (t *trustStore) review(script *remoteScript) *scriptReview {
+	trusted, known := t.Scripts[script.URL]
+	if known && trusted.SHA256 == script.SHA256 {
+		return nil
+	}
+
+	r := &scriptReview{URL: script.URL, SHA256: script.SHA256, Text: string(script.Data)}
+	if !known {
+		return r
+	}
+	r.Previous = trusted.SHA256
+	previous, err := os.ReadFile(t.contentPath(trusted.SHA256))
+	if err != nil {
+		return r
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(previous)),
+		B:        difflib.SplitLines(string(script.Data)),
+		FromFile: "approved " + trusted.SHA256[:12],
+		ToFile:   "current " + script.SHA256[:12],
+		Context:  3,
+	})
+	if err == nil {
+		r.Text = diff
+	}
+	return r
+}
+
+// approve records the current content of script as trusted.
+func (t *trustStore) approve(script *remoteScript) error {
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(t.contentPath(script.SHA256), script.Data, 0600); err != nil {
+		return err
+	}
+	previous := t.Scripts[script.URL].SHA256
+	t.Scripts[script.URL] = trustedScript{SHA256: script.SHA256, Approved: time.Now()}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := t.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, t.indexPath()); err != nil {
+		return err
+	}
+
+	// Only approved contents are needed for later diffs
+	if previous == "" || previous == script.SHA256 {
+		return nil
+	}
+	for _, other := range t.Scripts {
+		if other.SHA256 == previous {
+			return nil
+		}
+	}
+	os.Remove(t.contentPath(previous))
+	return nil
+}
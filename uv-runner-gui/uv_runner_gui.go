@@ -8,22 +8,15 @@
 */package main
 
 import (
-	"archive/tar"
-	"archive/zip"
-	"compress/gzip"
+	"bytes"
 	"context"
-	"crypto/sha256"
 	"fmt"
 	"image/color"
-	"io"
-	"net/http"
-	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
-	"runtime"
+	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -100,23 +93,39 @@ func (t *smartContrastTheme) Size(name fyne.ThemeSizeName) float32 {
 
 const uvVersion = "0.9.5"
 
+// App holds the widgets of the window; the session they present is kept by
+// the controller. Apart from appendOutput, its methods run on the UI thread.
 type App struct {
 	fyneApp         fyne.App
 	window          fyne.Window
 	scriptList      *widget.List
 	outputText      *widget.Entry
 	runButton       *widget.Button
+	prepareButton   *widget.Button
+	cacheButton     *widget.Button
 	addButton       *widget.Button
 	removeButton    *widget.Button
 	memoryPathEntry *widget.Entry
-	scripts         []string
-	uvPath          string
-	tempDir         string
-	selectedIdx     int         // Track selected item manually
-	outputBuffer    string      // Keep track of output text
-	outputMutex     sync.Mutex  // Protect output buffer
-	runningCmds     []*exec.Cmd // Track running processes
-	cmdsMutex       sync.Mutex  // Protect running commands slice
+	detailsLabel    *widget.Label
+	paramsBox       *fyne.Container
+	paramsFor       string                   // Script the parameter form is for
+	params          []ScriptParameter        // Parameters shown in the form
+	paramValues     map[string]func() string // Current form values by parameter name
+	requiresPython  string                   // requires-python of the script that runs
+	pythonSelect    *widget.Select
+	pythonRequests  map[string]string // --python value by option label
+	pythonsListed   bool
+	statusLabel     *widget.Label
+	statusProgress  *widget.ProgressBarInfinite
+	retryButton     *widget.Button
+	cancelButton    *widget.Button
+	localUVButton   *widget.Button
+	controller      *controller
+	payload         *payload
+	selectedIdx     int        // Track selected item manually
+	outputBuffer    string     // Keep track of output text
+	secrets         []string   // Passwords hidden in the output
+	outputMutex     sync.Mutex // Protect output buffer
 }
 
 func main() {
@@ -133,14 +142,40 @@ func main() {
 		window:       w,
 		selectedIdx:  -1, // No selection initially
 		outputBuffer: "",
-		runningCmds:  make([]*exec.Cmd, 0),
-		scripts: []string{
-			"https://raw.githubusercontent.com/tnldart/openapi-servers/refs/heads/main/servers/memory/oneshot.py",
-			"https://raw.githubusercontent.com/tnldart/openapi-servers/refs/heads/main/servers/memory/main.py",
-		},
 	}
 
 	app.setupUI()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		app.appendOutput(fmt.Sprintf("Error loading settings, using defaults: %v\n", err))
+		cfg = &Config{}
+	}
+	app.secrets = cfg.secrets()
+
+	// Adopt the descendants of scripts whose uv process exits, so they can be
+	// cleaned up
+	if err := becomeSubreaper(); err != nil {
+		app.appendOutput(fmt.Sprintf("Warning: cannot reap orphaned processes: %v\n", err))
+	}
+
+	// Scripts, uv and launcher settings may be embedded in this executable
+	embedded, err := openPayload()
+	if err != nil {
+		app.appendOutput(fmt.Sprintf("Error reading embedded payload: %v\n", err))
+	}
+	app.payload = embedded
+	if embedded != nil {
+		embedded.manifest.applyTo(cfg)
+		app.applyBranding()
+	}
+
+	defaultScripts := []string{
+		"https://raw.githubusercontent.com/tnldart/openapi-servers/refs/heads/main/servers/memory/oneshot.py",
+		"https://raw.githubusercontent.com/tnldart/openapi-servers/refs/heads/main/servers/memory/main.py",
+	}
+	app.controller = newController(cfg, embedded, defaultScripts, app.appendOutput, app.stateChanged, app.approveScript)
+
 	app.initializeUV()
 
 	// Set up cleanup on window close
@@ -155,20 +190,14 @@ func main() {
 func (a *App) setupUI() {
 	// Create script list
 	a.scriptList = widget.NewList(
-		func() int { return len(a.scripts) },
+		func() int { return a.controller.scriptCount() },
 		func() fyne.CanvasObject {
 			return widget.NewLabel("Template")
 		},
 		func(id widget.ListItemID, obj fyne.CanvasObject) {
 			label := obj.(*widget.Label)
-			if id < len(a.scripts) {
-				// Show just the filename or last part of URL
-				script := a.scripts[id]
-				if strings.Contains(script, "/") {
-					parts := strings.Split(script, "/")
-					script = parts[len(parts)-1]
-				}
-				label.SetText(script)
+			if script, ok := a.controller.scriptAt(id); ok {
+				label.SetText(scriptLabel(script))
 			}
 		},
 	)
@@ -176,16 +205,34 @@ func (a *App) setupUI() {
 	// Handle selection
 	a.scriptList.OnSelected = func(id widget.ListItemID) {
 		a.selectedIdx = id
+		a.showDetails()
 	}
 	a.scriptList.OnUnselected = func(id widget.ListItemID) {
 		a.selectedIdx = -1
+		a.showDetails()
 	}
 
+	// Details of the selected script
+	a.detailsLabel = widget.NewLabel("Select a script to see its details.")
+	a.detailsLabel.Wrapping = fyne.TextWrapWord
+
+	// Parameters declared by the script that runs
+	a.paramsBox = container.NewVBox()
+
 	// Create buttons
 	a.addButton = widget.NewButton("Add Script", a.addScript)
 	a.removeButton = widget.NewButton("Remove Selected", a.removeScript)
 	a.runButton = widget.NewButton("Run Scripts", a.runScripts)
 	a.runButton.Importance = widget.HighImportance
+	a.runButton.Disable()
+	a.prepareButton = widget.NewButton("Prepare", a.prepareScripts)
+	a.prepareButton.Disable()
+	a.cacheButton = widget.NewButton("Cache...", a.showCache)
+	a.cacheButton.Disable()
+
+	// Python to run with, listed once uv is ready
+	a.pythonSelect = widget.NewSelect([]string{defaultPython}, nil)
+	a.pythonSelect.SetSelected(defaultPython)
 
 	// Memory file path entry
 	a.memoryPathEntry = widget.NewEntry()
@@ -212,6 +259,17 @@ func (a *App) setupUI() {
 		a.fyneApp.Settings().SetTheme(&smartContrastTheme{})
 	})
 
+	// uv setup status and actions
+	a.statusLabel = widget.NewLabel("Setting up UV...")
+	a.statusLabel.Wrapping = fyne.TextWrapWord
+	a.statusProgress = widget.NewProgressBarInfinite()
+	a.retryButton = widget.NewButton("Retry", a.retryInitialize)
+	a.retryButton.Hide()
+	a.cancelButton = widget.NewButton("Cancel", func() {
+		a.controller.cancelInitialize()
+	})
+	a.localUVButton = widget.NewButton("Use Local uv...", a.chooseLocalUV)
+
 	// Create output area with MultiLine Entry for selectable text
 	a.outputText = widget.NewMultiLineEntry()
 	a.outputText.Wrapping = fyne.TextWrapWord
@@ -226,13 +284,19 @@ func (a *App) setupUI() {
 		nil, nil, widget.NewLabel("Memory File:"), browseButton,
 		a.memoryPathEntry,
 	)
+	pythonSection := container.NewBorder(
+		nil, nil, widget.NewLabel("Python:"), nil,
+		a.pythonSelect,
+	)
 	themeControls := container.NewHBox(lightThemeBtn, darkThemeBtn, autoThemeBtn)
 
+	scriptsAndDetails := container.NewHSplit(a.scriptList, container.NewVScroll(a.detailsLabel))
+	scriptsAndDetails.SetOffset(0.5)
 	scriptSection := container.NewBorder(
 		widget.NewLabel("Python Scripts:"),
-		container.NewVBox(scriptControls, memoryPathSection, themeControls),
+		container.NewVBox(scriptControls, a.paramsBox, pythonSection, memoryPathSection, themeControls),
 		nil, nil,
-		a.scriptList,
+		scriptsAndDetails,
 	)
 
 	outputSection := container.NewBorder(
@@ -247,9 +311,15 @@ func (a *App) setupUI() {
 	)
 	mainContent.SetOffset(0.4) // 40% for scripts, 60% for output
 
+	statusSection := container.NewBorder(
+		nil, a.statusProgress, nil,
+		container.NewHBox(a.retryButton, a.cancelButton, a.localUVButton),
+		a.statusLabel,
+	)
+
 	content := container.NewBorder(
-		nil,
-		a.runButton,
+		statusSection,
+		container.NewBorder(nil, nil, nil, container.NewHBox(a.prepareButton, a.cacheButton), a.runButton),
 		nil, nil,
 		mainContent,
 	)
@@ -257,490 +327,529 @@ func (a *App) setupUI() {
 	a.window.SetContent(content)
 }
 
+// applyBranding shows the name and icon of a launcher built with
+// `uv-runner-cli pack`.
+func (a *App) applyBranding() {
+	manifest := a.payload.manifest
+	if manifest.Name != "" {
+		a.window.SetTitle(manifest.Name)
+	}
+	if manifest.Icon != "" {
+		var icon bytes.Buffer
+		if err := a.payload.extractTo(manifest.Icon, &icon); err != nil {
+			a.appendOutput(fmt.Sprintf("Error loading launcher icon: %v\n", err))
+			return
+		}
+		resource := fyne.NewStaticResource(path.Base(manifest.Icon), icon.Bytes())
+		a.fyneApp.SetIcon(resource)
+		a.window.SetIcon(resource)
+	}
+}
+
+// showDetails fills the details pane for the selected script: where it comes
+// from and what its inline metadata declares.
+func (a *App) showDetails() {
+	ref, ok := a.controller.scriptAt(a.selectedIdx)
+	if !ok {
+		a.detailsLabel.SetText("Select a script to see its details.")
+		return
+	}
+
+	if tool := parseToolEntry(ref); tool != nil {
+		a.detailsLabel.SetText(fmt.Sprintf("Tool: %s\n\nRuns: uv tool run %s", tool.name(), strings.Join(tool.Args, " ")))
+		return
+	}
+
+	location := ref
+	if !isRemoteScript(ref) {
+		if abs, err := filepath.Abs(ref); err == nil {
+			location = abs
+		}
+	}
+	a.detailsLabel.SetText(fmt.Sprintf("Source: %s\n\nReading script...", location))
+
+	go func() {
+		metadata, err := a.controller.metadata(context.Background(), ref)
+
+		var details strings.Builder
+		fmt.Fprintf(&details, "Source: %s\n\n", location)
+		switch {
+		case err != nil:
+			fmt.Fprintf(&details, "Could not read script: %v", err)
+		case metadata == nil:
+			details.WriteString("The script declares no inline metadata.")
+		default:
+			python := metadata.RequiresPython
+			if python == "" {
+				python = "any version"
+			}
+			fmt.Fprintf(&details, "Python: %s\n\n", python)
+			if len(metadata.Dependencies) == 0 {
+				details.WriteString("Dependencies: none")
+			} else {
+				details.WriteString("Dependencies:\n  " + strings.Join(metadata.Dependencies, "\n  "))
+			}
+		}
+
+		fyne.Do(func() {
+			// The selection may have changed in the meantime
+			if current, ok := a.controller.scriptAt(a.selectedIdx); ok && current == ref {
+				a.detailsLabel.SetText(details.String())
+			}
+		})
+	}()
+}
+
 func (a *App) addScript() {
 	entry := widget.NewEntry()
 	entry.SetPlaceHolder("Enter script URL or local path...")
 	entry.Resize(fyne.NewSize(400, entry.MinSize().Height))
 
-	dialog.ShowForm("Add Script", "Add", "Cancel", []*widget.FormItem{
+	// A tool runs a package's command, e.g. ruff@0.6.9, with its own arguments
+	toolArgs := widget.NewEntry()
+	toolArgs.SetPlaceHolder("Arguments for the tool")
+	kind := widget.NewRadioGroup([]string{"Script", "Tool"}, nil)
+	kind.Horizontal = true
+	kind.SetSelected("Script")
+
+	form := dialog.NewForm("Add Script", "Add", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Type", kind),
 		widget.NewFormItem("Script URL/Path", entry),
+		widget.NewFormItem("Arguments", toolArgs),
 	}, func(ok bool) {
-		if ok && entry.Text != "" {
-			a.scripts = append(a.scripts, entry.Text)
-			a.scriptList.Refresh()
+		if !ok || strings.TrimSpace(entry.Text) == "" {
+			return
+		}
+		if kind.Selected == "Tool" {
+			a.controller.addScript(strings.TrimSpace(toolPrefix + entry.Text + " " + toolArgs.Text))
+		} else {
+			a.controller.addScript(entry.Text)
 		}
 	}, a.window)
+	kind.OnChanged = func(selected string) {
+		if selected == "Tool" {
+			entry.SetPlaceHolder("Package command, optionally pinned, e.g. ruff@0.6.9")
+			toolArgs.Enable()
+		} else {
+			entry.SetPlaceHolder("Enter script URL or local path...")
+			toolArgs.Disable()
+		}
+	}
+	toolArgs.Disable()
+	form.Show()
 }
 
 func (a *App) removeScript() {
-	if a.selectedIdx < 0 || a.selectedIdx >= len(a.scripts) {
+	// Remove selected item
+	if !a.controller.removeScript(a.selectedIdx) {
 		dialog.ShowInformation("No Selection", "Please select a script to remove.", a.window)
 		return
 	}
-
-	// Remove selected item
-	a.scripts = append(a.scripts[:a.selectedIdx], a.scripts[a.selectedIdx+1:]...)
 	a.selectedIdx = -1
-	a.scriptList.Refresh()
+	a.scriptList.UnselectAll()
+	a.showDetails()
 }
 
 func (a *App) cleanup() {
 	a.appendOutput("Cleaning up processes...\n")
-
-	a.cmdsMutex.Lock()
-	defer a.cmdsMutex.Unlock()
-
-	for _, cmd := range a.runningCmds {
-		if cmd.Process != nil {
-			// On macOS/Unix, kill the process group to ensure child processes are terminated
-			if runtime.GOOS != "windows" {
-				// Kill the process group (negative PID kills the process group)
-				if err := exec.Command("kill", "-TERM", fmt.Sprintf("-%d", cmd.Process.Pid)).Run(); err == nil {
-					// Wait a bit for graceful termination
-					time.Sleep(100 * time.Millisecond)
-				}
-				// Force kill if still running
-				exec.Command("kill", "-KILL", fmt.Sprintf("-%d", cmd.Process.Pid)).Run()
-			} else {
-				// On Windows, use taskkill to terminate the process tree
-				exec.Command("taskkill", "/F", "/T", "/PID", fmt.Sprintf("%d", cmd.Process.Pid)).Run()
-			}
-
-			// Also try direct process kill as fallback
-			cmd.Process.Kill()
-		}
-	}
-
-	// Clean up temp directory if it exists
-	if a.tempDir != "" {
-		os.RemoveAll(a.tempDir)
-	}
-
-	a.runningCmds = make([]*exec.Cmd, 0)
+	a.controller.shutdown()
 	a.appendOutput("Cleanup completed.\n")
 }
 
-func (a *App) addRunningCmd(cmd *exec.Cmd) {
-	a.cmdsMutex.Lock()
-	defer a.cmdsMutex.Unlock()
-	a.runningCmds = append(a.runningCmds, cmd)
+// stateChanged updates the widgets after the session changes.
+func (a *App) stateChanged(sessionState) {
+	fyne.Do(func() {
+		a.scriptList.Refresh()
+		a.updateStatus()
+		a.updateParameters()
+		a.listPythons()
+	})
 }
 
-func (a *App) removeRunningCmd(cmd *exec.Cmd) {
-	a.cmdsMutex.Lock()
-	defer a.cmdsMutex.Unlock()
-	for i, c := range a.runningCmds {
-		if c == cmd {
-			a.runningCmds = append(a.runningCmds[:i], a.runningCmds[i+1:]...)
-			break
-		}
-	}
-}
+// defaultPython is the Python option that leaves the choice to the python
+// settings, or to uv.
+const defaultPython = "Default"
 
-func (a *App) setupProcessGroup(cmd *exec.Cmd) {
-	// On Unix systems, create a new process group so we can kill
-	// the entire group (including child processes) when needed
-	if runtime.GOOS != "windows" {
-		a.setupUnixProcessGroup(cmd)
+// listPythons fills the Python options from uv once it is ready.
+func (a *App) listPythons() {
+	if a.pythonsListed {
+		return
 	}
-}
-
-func (a *App) initializeUV() {
-	a.appendOutput("Initializing UV Python package manager...\n")
+	if state, _ := a.controller.current(); state != stateReady {
+		return
+	}
+	a.pythonsListed = true
 
 	go func() {
-		// Create temp directory
-		tempDir, err := os.MkdirTemp("", "uv-runner-*")
+		installations, err := a.controller.pythons(context.Background())
 		if err != nil {
-			a.appendOutput(fmt.Sprintf("Error creating temp directory: %v\n", err))
+			a.appendOutput(fmt.Sprintf("Error listing Python versions: %v\n", err))
 			return
 		}
-		a.tempDir = tempDir
-
-		// Download and extract uv
-		uvPath, err := a.downloadUV(tempDir)
-		if err != nil {
-			a.appendOutput(fmt.Sprintf("Error downloading UV: %v\n", err))
-			return
+		options := []string{defaultPython}
+		requests := map[string]string{}
+		for _, p := range installations {
+			label := p.request()
+			if p.Path != nil {
+				label += " (installed)"
+			}
+			if _, dup := requests[label]; !dup {
+				requests[label] = p.request()
+				options = append(options, label)
+			}
 		}
-
-		a.uvPath = uvPath
-		a.appendOutput("UV initialized successfully!\n")
-
-		// Enable run button using fyne.Do
 		fyne.Do(func() {
-			a.runButton.Enable()
+			a.pythonRequests = requests
+			a.pythonSelect.Options = options
+			a.pythonSelect.Refresh()
 		})
 	}()
 }
 
-func (a *App) runScripts() {
-	if a.uvPath == "" {
-		dialog.ShowError(fmt.Errorf("UV not initialized yet"), a.window)
-		return
+// scriptLabel shows just the filename or last part of URL, or the tool.
+func scriptLabel(script string) string {
+	if tool := parseToolEntry(script); tool != nil {
+		return tool.name() + " (tool)"
 	}
+	if strings.Contains(script, "/") {
+		parts := strings.Split(script, "/")
+		script = parts[len(parts)-1]
+	}
+	return script
+}
 
-	if len(a.scripts) == 0 {
-		dialog.ShowInformation("No Scripts", "Please add some scripts to run.", a.window)
+// updateParameters shows a form for the parameters declared by the script
+// that runs, the first one, once it changes.
+func (a *App) updateParameters() {
+	ref, _ := a.controller.scriptAt(0)
+	if ref == a.paramsFor {
 		return
 	}
-
-	a.runButton.Disable()
-
-	// Clean up any existing running processes before starting new ones
-	a.cmdsMutex.Lock()
-	if len(a.runningCmds) > 0 {
-		a.appendOutput("Stopping existing processes...\n")
-		for _, cmd := range a.runningCmds {
-			if cmd.Process != nil {
-				if runtime.GOOS != "windows" {
-					exec.Command("kill", "-TERM", fmt.Sprintf("-%d", cmd.Process.Pid)).Run()
-				} else {
-					exec.Command("taskkill", "/F", "/T", "/PID", fmt.Sprintf("%d", cmd.Process.Pid)).Run()
-				}
-				cmd.Process.Kill()
-			}
-		}
-		a.runningCmds = make([]*exec.Cmd, 0)
+	a.paramsFor = ref
+	a.requiresPython = ""
+	a.showParameters(nil)
+	if ref == "" {
+		return
 	}
-	a.cmdsMutex.Unlock()
-
-	a.outputBuffer = "" // Clear output
-	fyne.Do(func() {
-		a.outputText.SetText("")
-	})
-	a.appendOutput("Starting script execution...\n")
 
 	go func() {
-		defer func() {
-			fyne.Do(func() {
-				a.runButton.Enable()
-			})
-		}()
-
-		// Build command: uv run <scripts...>
-		args := append([]string{"run"}, a.scripts...)
-
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
-
-		cmd := exec.CommandContext(ctx, a.uvPath, args...)
-
-		// Set up environment with MEMORY_FILE_PATH if specified
-		cmd.Env = os.Environ()
-		if a.memoryPathEntry.Text != "" {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("MEMORY_FILE_PATH=%s", a.memoryPathEntry.Text))
-		}
-
-		// Set up process group for proper cleanup on Unix systems
-		if runtime.GOOS != "windows" {
-			a.setupProcessGroup(cmd)
-		}
-
-		// Create pipes for stdout and stderr
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			a.appendOutput(fmt.Sprintf("Error creating stdout pipe: %v\n", err))
-			return
-		}
-
-		stderr, err := cmd.StderrPipe()
+		metadata, err := a.controller.metadata(context.Background(), ref)
 		if err != nil {
-			a.appendOutput(fmt.Sprintf("Error creating stderr pipe: %v\n", err))
+			a.appendOutput(fmt.Sprintf("Error reading parameters of %s: %v\n", ref, err))
 			return
 		}
-
-		// Start the command
-		if err := cmd.Start(); err != nil {
-			a.appendOutput(fmt.Sprintf("Error starting command: %v\n", err))
+		if metadata == nil {
 			return
 		}
-
-		// Track the running command
-		a.addRunningCmd(cmd)
-
-		// Read output in goroutines
-		var wg sync.WaitGroup
-		wg.Add(2)
-
-		go func() {
-			defer wg.Done()
-			a.readOutput(stdout, "STDOUT")
-		}()
-
-		go func() {
-			defer wg.Done()
-			a.readOutput(stderr, "STDERR")
-		}()
-
-		// Wait for output readers to finish
-		wg.Wait()
-
-		// Wait for completion
-		if err := cmd.Wait(); err != nil {
-			a.appendOutput(fmt.Sprintf("Command finished with error: %v\n", err))
-		} else {
-			a.appendOutput("Scripts completed successfully!\n")
-		}
-
-		// Remove from tracking when completed
-		a.removeRunningCmd(cmd)
+		fyne.Do(func() {
+			if a.paramsFor == ref {
+				a.requiresPython = metadata.RequiresPython
+				a.showParameters(metadata.Runner.Parameters)
+			}
+		})
 	}()
 }
 
-func (a *App) readOutput(reader io.Reader, prefix string) {
-	buf := make([]byte, 1024)
-	for {
-		n, err := reader.Read(buf)
-		if n > 0 {
-			output := string(buf[:n])
-			a.appendOutput(output)
-		}
-		if err != nil {
-			if err != io.EOF {
-				a.appendOutput(fmt.Sprintf("%s read error: %v\n", prefix, err))
+// showParameters replaces the parameter form.
+func (a *App) showParameters(params []ScriptParameter) {
+	a.params = params
+	a.paramValues = map[string]func() string{}
+	a.paramsBox.RemoveAll()
+	if len(params) == 0 {
+		return
+	}
+
+	form := widget.NewForm()
+	for i := range params {
+		p := &params[i]
+		var input fyne.CanvasObject
+		switch {
+		case p.Type == paramBool:
+			check := widget.NewCheck("", nil)
+			check.Checked, _ = strconv.ParseBool(p.defaultValue())
+			a.paramValues[p.Name] = func() string { return strconv.FormatBool(check.Checked) }
+			input = check
+		case len(p.Choices) > 0:
+			choice := widget.NewSelect(p.Choices, nil)
+			choice.Selected = p.defaultValue()
+			a.paramValues[p.Name] = func() string { return choice.Selected }
+			input = choice
+		default:
+			entry := widget.NewEntry()
+			entry.SetText(p.defaultValue())
+			entry.Validator = func(value string) error {
+				if value == "" {
+					return nil
+				}
+				return p.check(value)
+			}
+			a.paramValues[p.Name] = func() string { return entry.Text }
+			input = entry
+			if p.Type == paramFile {
+				browse := widget.NewButton("Browse", func() {
+					dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+						if err == nil && reader != nil {
+							entry.SetText(reader.URI().Path())
+							reader.Close()
+						}
+					}, a.window)
+				})
+				input = container.NewBorder(nil, nil, nil, browse, entry)
 			}
-			break
 		}
+		item := widget.NewFormItem(p.Name, input)
+		item.HintText = p.Help
+		form.AppendItem(item)
 	}
+	a.paramsBox.Add(widget.NewLabel(fmt.Sprintf("Parameters of %s:", scriptLabel(a.paramsFor))))
+	a.paramsBox.Add(form)
 }
 
-func (a *App) appendOutput(text string) {
-	// Thread-safe buffer update
-	a.outputMutex.Lock()
-	a.outputBuffer += text
-	newText := a.outputBuffer
-	a.outputMutex.Unlock()
-
-	// Use Fyne's proper threading API
-	fyne.Do(func() {
-		a.outputText.SetText(newText)
-		// Scroll to bottom
-		a.outputText.CursorRow = len(strings.Split(newText, "\n"))
-	})
-}
-
-func (a *App) downloadUV(tempDir string) (string, error) {
-	platform := getPlatform()
-	arch := getArch()
-	target := fmt.Sprintf("%s-%s", arch, platform)
-
-	a.appendOutput(fmt.Sprintf("Detected platform: %s\n", target))
-
-	// Determine file extension based on platform
-	var fileExt string
-	var tmpFilePattern string
-	if runtime.GOOS == "windows" {
-		fileExt = ".zip"
-		tmpFilePattern = "uv-*.zip"
-	} else {
-		fileExt = ".tar.gz"
-		tmpFilePattern = "uv-*.tar.gz"
-	}
-
-	url := fmt.Sprintf("https://github.com/astral-sh/uv/releases/download/%s/uv-%s%s", uvVersion, target, fileExt)
-	checksumURL := url + ".sha256"
-
-	a.appendOutput(fmt.Sprintf("Downloading UV from: %s\n", url))
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", err
+// updateStatus shows the current session state and the actions it allows.
+func (a *App) updateStatus() {
+	state, err := a.controller.current()
+	switch state {
+	case stateInitializing:
+		a.statusLabel.SetText("Setting up UV...")
+	case stateFailed:
+		if err == errSetUpCancelled {
+			a.statusLabel.SetText("UV setup was cancelled.")
+		} else {
+			a.statusLabel.SetText(fmt.Sprintf("UV setup failed: %v", err))
+		}
+	case stateReady:
+		a.statusLabel.SetText("UV ready: " + a.controller.uv())
+	case stateRunning:
+		a.statusLabel.SetText("Running scripts with UV: " + a.controller.uv())
+	case statePreparing:
+		a.statusLabel.SetText("Preparing script dependencies...")
+	case stateCleaning:
+		a.statusLabel.SetText("Cleaning the uv cache...")
+	}
+
+	showIf := func(w fyne.CanvasObject, visible bool) {
+		if visible {
+			w.Show()
+		} else {
+			w.Hide()
+		}
 	}
-	defer resp.Body.Close()
+	showIf(a.statusProgress, state == stateInitializing || state == statePreparing || state == stateCleaning)
+	showIf(a.cancelButton, state == stateInitializing)
+	showIf(a.retryButton, state == stateFailed)
+	showIf(a.localUVButton, state == stateInitializing || state == stateFailed)
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to download uv: status %d", resp.StatusCode)
+	if state == stateReady {
+		a.runButton.Enable()
+		a.prepareButton.Enable()
+		a.cacheButton.Enable()
+	} else {
+		a.runButton.Disable()
+		a.prepareButton.Disable()
+		a.cacheButton.Disable()
 	}
+}
 
-	// Create a temporary file to store the downloaded archive
-	tmpFile, err := os.CreateTemp(tempDir, tmpFilePattern)
-	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
-	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
+func (a *App) initializeUV() {
+	a.appendOutput("Initializing UV Python package manager...\n")
 
-	// Download to temp file and calculate checksum
-	hasher := sha256.New()
-	multiWriter := io.MultiWriter(tmpFile, hasher)
+	go func() {
+		a.checkSurvivors()
+		if err := a.controller.initialize(); err != nil {
+			a.appendOutput(fmt.Sprintf("Error initializing UV: %v\n", err))
+		}
+	}()
+}
 
-	_, err = io.Copy(multiWriter, resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to save download: %w", err)
+func (a *App) retryInitialize() {
+	a.appendOutput("Retrying UV setup...\n")
+	if err := a.controller.initialize(); err != nil {
+		dialog.ShowError(err, a.window)
 	}
+}
 
-	// Calculate the actual checksum
-	actualChecksum := fmt.Sprintf("%x", hasher.Sum(nil))
+// chooseLocalUV lets the user pick a uv binary instead of the one being set
+// up, e.g. when downloads are blocked.
+func (a *App) chooseLocalUV() {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		path := reader.URI().Path()
+		reader.Close()
+		go func() {
+			if err := a.controller.useLocalUV(path); err != nil {
+				fyne.Do(func() {
+					dialog.ShowError(err, a.window)
+				})
+			}
+		}()
+	}, a.window)
+}
 
-	// Download and verify checksum
-	a.appendOutput("Verifying checksum...\n")
-	checksumResp, err := http.Get(checksumURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to download checksum: %w", err)
+func (a *App) runScripts() {
+	// Set up environment with MEMORY_FILE_PATH if specified
+	var env []string
+	if a.memoryPathEntry.Text != "" {
+		env = append(env, fmt.Sprintf("MEMORY_FILE_PATH=%s", a.memoryPathEntry.Text))
 	}
-	defer checksumResp.Body.Close()
 
-	if checksumResp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to download checksum: status %d", checksumResp.StatusCode)
+	// Pass the values of the script's parameters
+	values := map[string]string{}
+	for name, value := range a.paramValues {
+		values[name] = value()
 	}
-
-	checksumBytes, err := io.ReadAll(checksumResp.Body)
+	args, paramEnv, err := parameterArgs(a.params, values)
 	if err != nil {
-		return "", fmt.Errorf("failed to read checksum: %w", err)
+		dialog.ShowError(err, a.window)
+		return
 	}
+	env = append(env, paramEnv...)
 
-	// Parse expected checksum (typically in format: "checksum filename")
-	expectedChecksum := strings.Fields(string(checksumBytes))[0]
-
-	// Verify checksum
-	if actualChecksum != expectedChecksum {
-		return "", fmt.Errorf("checksum verification failed: expected %s, got %s", expectedChecksum, actualChecksum)
+	// A Python chosen for this run must suit the script
+	python := a.pythonRequests[a.pythonSelect.Selected]
+	if err := checkRequiresPython(a.requiresPython, python); err != nil {
+		dialog.ShowError(err, a.window)
+		return
 	}
 
-	a.appendOutput("Checksum verification successful\n")
-	a.appendOutput("Extracting UV binary...\n")
+	a.outputMutex.Lock()
+	a.outputBuffer = "" // Clear output
+	a.outputMutex.Unlock()
+	a.outputText.SetText("")
 
-	// Reset file position for reading
-	_, err = tmpFile.Seek(0, 0)
-	if err != nil {
-		return "", fmt.Errorf("failed to reset file position: %w", err)
+	err = a.controller.run(python, args, env)
+	if err == errNoScripts {
+		dialog.ShowInformation("No Scripts", "Please add some scripts to run.", a.window)
+	} else if err != nil {
+		dialog.ShowError(err, a.window)
 	}
+}
 
-	// Extract archive based on platform
-	if runtime.GOOS == "windows" {
-		return a.extractZip(tmpFile, tempDir)
-	} else {
-		return a.extractTarGz(tmpFile, tempDir)
+// prepareScripts caches the dependencies of every script in the list, so that
+// they start quickly and can later run offline.
+func (a *App) prepareScripts() {
+	err := a.controller.prepare()
+	if err == errNoScripts {
+		dialog.ShowInformation("No Scripts", "Please add some scripts to prepare.", a.window)
+	} else if err != nil {
+		dialog.ShowError(err, a.window)
 	}
 }
 
-func (a *App) extractTarGz(file *os.File, tempDir string) (string, error) {
-	// Extract tar.gz from the verified file
-	gzr, err := gzip.NewReader(file)
+// showCache shows the size of uv's cache and offers to prune or wipe it.
+func (a *App) showCache() {
+	cache, err := a.controller.cache()
 	if err != nil {
-		return "", err
+		dialog.ShowError(err, a.window)
+		return
 	}
-	defer gzr.Close()
 
-	tr := tar.NewReader(gzr)
-
-	var uvPath string
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return "", err
-		}
-
-		// Look for uv executable (could be nested in a directory)
-		if filepath.Base(header.Name) == "uv" || filepath.Base(header.Name) == "uv.exe" {
-			uvPath = filepath.Join(tempDir, filepath.Base(header.Name))
-
-			file, err := os.Create(uvPath)
-			if err != nil {
-				return "", err
-			}
-
-			_, err = io.Copy(file, tr)
-			file.Close()
+	go func() {
+		report, err := cache.report(context.Background())
+		fyne.Do(func() {
 			if err != nil {
-				return "", err
+				dialog.ShowError(err, a.window)
+				return
 			}
-
-			// Make executable
-			err = os.Chmod(uvPath, 0755)
-			if err != nil {
-				return "", err
+			var d dialog.Dialog
+			clean := func(wipe bool) {
+				d.Hide()
+				if err := a.controller.cleanCache(wipe); err != nil {
+					dialog.ShowError(err, a.window)
+				}
 			}
+			prune := widget.NewButton("Prune", func() { clean(false) })
+			wipe := widget.NewButton("Wipe", func() {
+				dialog.ShowConfirm("Wipe Cache",
+					"Remove the whole uv cache? Script dependencies will be downloaded again.",
+					func(ok bool) {
+						if ok {
+							clean(true)
+						}
+					}, a.window)
+			})
+			wipe.Importance = widget.DangerImportance
+			content := container.NewVBox(widget.NewLabel(report), container.NewHBox(prune, wipe))
+			d = dialog.NewCustom("uv Cache", "Close", content, a.window)
+			d.Show()
+		})
+	}()
+}
 
-			break
-		}
-	}
+func (a *App) appendOutput(text string) {
+	// Never show credentials, whether from the settings or in URLs
+	text = redact(text, a.secrets...)
 
-	if uvPath == "" {
-		return "", fmt.Errorf("uv binary not found in archive")
-	}
+	// Thread-safe buffer update
+	a.outputMutex.Lock()
+	a.outputBuffer += text
+	newText := a.outputBuffer
+	a.outputMutex.Unlock()
 
-	return uvPath, nil
+	// Use Fyne's proper threading API
+	fyne.Do(func() {
+		a.outputText.SetText(newText)
+		// Scroll to bottom
+		a.outputText.CursorRow = len(strings.Split(newText, "\n"))
+	})
 }
 
-func (a *App) extractZip(file *os.File, tempDir string) (string, error) {
-	// Get file info for zip reader
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return "", err
-	}
+// approveScript shows a remote script that is new or has changed and waits
+// for the user to approve running it.
+func (a *App) approveScript(review *scriptReview) bool {
+	answer := make(chan bool, 1)
+	fyne.Do(func() {
+		text := widget.NewTextGridFromString(review.Text)
+		scroll := container.NewScroll(text)
+		scroll.SetMinSize(fyne.NewSize(640, 360))
+		content := container.NewBorder(
+			widget.NewLabel(fmt.Sprintf("%s\nSHA-256: %s\n\nReview it before running it:", review.Title(), review.SHA256)),
+			nil, nil, nil,
+			scroll,
+		)
+		confirm := dialog.NewCustomConfirm("Approve Script", "Approve and Run", "Don't Run", content, func(ok bool) {
+			answer <- ok
+		}, a.window)
+		confirm.Show()
+	})
+	return <-answer
+}
 
-	// Create zip reader
-	zr, err := zip.NewReader(file, fileInfo.Size())
+// checkSurvivors looks for servers that an earlier session left running when
+// it crashed, and lets the user terminate them or keep them under this
+// session's control.
+func (a *App) checkSurvivors() {
+	survivors, err := survivingProcesses(true)
 	if err != nil {
-		return "", err
-	}
-
-	var uvPath string
-	for _, f := range zr.File {
-		// Look for uv executable (could be nested in a directory)
-		if filepath.Base(f.Name) == "uv.exe" || filepath.Base(f.Name) == "uv" {
-			uvPath = filepath.Join(tempDir, filepath.Base(f.Name))
-
-			rc, err := f.Open()
-			if err != nil {
-				return "", err
-			}
-
-			outFile, err := os.Create(uvPath)
-			if err != nil {
-				rc.Close()
-				return "", err
-			}
-
-			_, err = io.Copy(outFile, rc)
-			outFile.Close()
-			rc.Close()
-			if err != nil {
-				return "", err
-			}
-
-			// Make executable
-			err = os.Chmod(uvPath, 0755)
-			if err != nil {
-				return "", err
-			}
-
-			break
-		}
+		a.appendOutput(fmt.Sprintf("Error checking for processes from earlier sessions: %v\n", err))
+		return
 	}
-
-	if uvPath == "" {
-		return "", fmt.Errorf("uv binary not found in archive")
+	if len(survivors) == 0 {
+		return
 	}
 
-	return uvPath, nil
-}
-
-func getPlatform() string {
-	switch runtime.GOOS {
-	case "darwin":
-		return "apple-darwin"
-	case "linux":
-		return "unknown-linux-gnu"
-	case "windows":
-		return "pc-windows-msvc"
-	default:
-		panic(fmt.Sprintf("Unsupported platform: %s", runtime.GOOS))
+	lines := make([]string, len(survivors))
+	for i, r := range survivors {
+		lines[i] = r.String()
 	}
-}
+	message := "Processes started by an earlier session are still running:\n\n" + strings.Join(lines, "\n") +
+		"\n\nTerminate them, or keep them running and stop them with this session?"
 
-func getArch() string {
-	switch runtime.GOARCH {
-	case "amd64":
-		return "x86_64"
-	case "arm64":
-		return "aarch64"
-	default:
-		panic(fmt.Sprintf("Unsupported architecture: %s", runtime.GOARCH))
-	}
+	fyne.Do(func() {
+		confirm := dialog.NewConfirm("Processes Still Running", message, func(terminate bool) {
+			go func() {
+				if terminate {
+					a.controller.stopRecorded(survivors)
+					return
+				}
+				for _, r := range survivors {
+					a.controller.adopt(r)
+				}
+			}()
+		}, a.window)
+		confirm.SetConfirmText("Terminate")
+		confirm.SetDismissText("Keep Running")
+		confirm.Show()
+	})
 }